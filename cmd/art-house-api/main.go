@@ -12,8 +12,12 @@ import (
 	"github.com/iamnotrodger/art-house-api/internal/artist"
 	"github.com/iamnotrodger/art-house-api/internal/artwork"
 	"github.com/iamnotrodger/art-house-api/internal/exhibition"
+	"github.com/iamnotrodger/art-house-api/internal/export"
 	"github.com/iamnotrodger/art-house-api/internal/health"
 	"github.com/iamnotrodger/art-house-api/internal/middleware"
+	"github.com/iamnotrodger/art-house-api/internal/revision"
+	"github.com/iamnotrodger/art-house-api/internal/search"
+	"github.com/iamnotrodger/art-house-api/internal/suggest"
 	"github.com/iamnotrodger/art-house-api/internal/util"
 	"github.com/rs/cors"
 )
@@ -21,7 +25,8 @@ import (
 func main() {
 	config.LoadConfig()
 
-	ctx, _ := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 	client, err := util.GetMongoClient(ctx)
 	if err != nil {
 		log.Fatal(err)
@@ -32,17 +37,39 @@ func main() {
 	rdb := util.GetRedisClient()
 
 	// TODO: create app context to hold all the db and cache
-	artworkStore := artwork.NewStore(db)
+	revisionStore := revision.NewStore(db)
+	// revisions that were numbered before the index existed can keep it from being created
+	err = revisionStore.CreateIndexes(ctx)
+	if err != nil {
+		log.Println(err)
+	}
+
 	artworkCache := artwork.NewCache(rdb, time.Minute)
-	artworkHandler := artwork.NewHandler(artworkStore, artworkCache)
+	artistCache := artist.NewCache(rdb, time.Minute)
+	exhibitionCache := exhibition.NewCache(rdb, time.Minute)
+
+	artworkStore := artwork.NewStore(db)
+	artworkHandler := artwork.NewHandler(artworkStore, artworkCache, artistCache, exhibitionCache, revisionStore)
+	artworkRevisionHandler := revision.NewHandler(revisionStore, "artworks", artworkHandler, artworkStore)
 
 	artistStore := artist.NewStore(db)
-	artistCache := artist.NewCache(rdb, time.Minute)
-	artistHandler := artist.NewHandler(artistStore, artistCache)
+	artistHandler := artist.NewHandler(artistStore, artistCache, artworkCache, exhibitionCache, revisionStore)
+	artistRevisionHandler := revision.NewHandler(revisionStore, "artists", artistHandler, artistStore)
 
 	exhibitionStore := exhibition.NewStore(db)
-	exhibitionCache := exhibition.NewCache(rdb, time.Minute)
-	exhibitionHandler := exhibition.NewHandler(exhibitionStore, exhibitionCache)
+	exhibitionHandler := exhibition.NewHandler(exhibitionStore, exhibitionCache, revisionStore)
+	exhibitionRevisionHandler := revision.NewHandler(revisionStore, "exhibitions", exhibitionHandler, exhibitionStore)
+
+	exportHandler := export.NewHandler(artworkStore, artistStore, exhibitionStore)
+
+	searchStore := search.NewStore(db)
+	searchCache := search.NewCache(rdb, time.Minute)
+	searchHandler := search.NewHandler(searchStore, searchCache)
+
+	suggestStore := suggest.NewStore(db)
+	suggestIndex := suggest.NewIndex(rdb, 15*time.Minute)
+	suggestHandler := suggest.NewHandler(suggestStore, suggestIndex)
+	go suggestHandler.Refresh(context.Background(), 5*time.Minute)
 
 	router := mux.NewRouter().StrictSlash(true)
 	router.Use(middleware.LoggingMiddleware)
@@ -55,12 +82,35 @@ func main() {
 	artistHandler.RegisterRoutes(router)
 	//Exhibition Routes
 	exhibitionHandler.RegisterRoutes(router)
+	//Export Routes
+	exportHandler.RegisterRoutes(router)
+	//Search Routes
+	searchHandler.RegisterRoutes(router)
+	suggestHandler.RegisterRoutes(router)
+	//Revision Routes
+	artworkRevisionHandler.RegisterRoutes(router, "/api/artwork")
+	artistRevisionHandler.RegisterRoutes(router, "/api/artists")
+	exhibitionRevisionHandler.RegisterRoutes(router, "/api/exhibitions")
+
+	corsHandler := cors.New(cors.Options{
+		AllowedMethods: []string{
+			http.MethodHead,
+			http.MethodGet,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodPatch,
+			http.MethodDelete,
+		},
+		AllowedHeaders: []string{"Accept", "Content-Type", "X-Requested-With", "If-Match", "If-None-Match", revision.AuthorHeader, util.APIVersionHeader},
+		ExposedHeaders: []string{"ETag", "Link", util.NextCursorHeader},
+	})
 
 	server := &http.Server{
-		Handler:      cors.Default().Handler(router),
+		Handler:      corsHandler.Handler(router),
 		Addr:         fmt.Sprintf("0.0.0.0:%v", config.Global.Port),
 		WriteTimeout: 15 * time.Second,
 		ReadTimeout:  15 * time.Second,
+		ConnContext:  middleware.ConnContext,
 	}
 	log.Println("API Started. Listening on", config.Global.Port)
 	log.Fatal(server.ListenAndServe())
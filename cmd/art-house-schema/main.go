@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/iamnotrodger/art-house-api/cmd/config"
+	"github.com/iamnotrodger/art-house-api/internal/schema"
+	"github.com/iamnotrodger/art-house-api/internal/util"
+)
+
+func main() {
+	level := flag.String("level", "moderate", "validation level of the collections, strict or moderate")
+	action := flag.String("action", "error", "validation action of the collections, error or warn")
+	limit := flag.Int64("limit", 20, "maximum number of violating documents listed per collection, 0 lists all of them")
+	dryRun := flag.Bool("dry-run", false, "only report the documents that violate the schemas")
+	flag.Parse()
+
+	if err := validateFlags(*level, *action, *limit); err != nil {
+		log.Fatal(err)
+	}
+
+	config.LoadConfig()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	client, err := util.GetMongoClient(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+	db := client.Database(config.Global.MongoDBName)
+
+	for _, collection := range schema.Collections {
+		if !*dryRun {
+			created, err := schema.Sync(ctx, db, collection, *level, *action)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if created {
+				log.Printf("%s: created with validator", collection.Name)
+			} else {
+				log.Printf("%s: validator updated", collection.Name)
+			}
+		}
+
+		count, ids, err := schema.FindViolations(ctx, db, collection, *limit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, message := range report(collection.Name, count, ids) {
+			log.Println(message)
+		}
+	}
+}
+
+func validateFlags(level string, action string, limit int64) error {
+	if level != "strict" && level != "moderate" {
+		return fmt.Errorf("invalid level %q, must be strict or moderate", level)
+	}
+	if action != "error" && action != "warn" {
+		return fmt.Errorf("invalid action %q, must be error or warn", action)
+	}
+	if limit < 0 {
+		return fmt.Errorf("invalid limit %d, must not be negative", limit)
+	}
+	return nil
+}
+
+// report lists the documents of a collection that violate its schema
+func report(name string, count int64, ids []interface{}) []string {
+	if count == 0 {
+		return []string{fmt.Sprintf("%s: no documents violate the schema", name)}
+	}
+
+	messages := []string{fmt.Sprintf("%s: %d documents violate the schema", name, count)}
+	for _, id := range ids {
+		messages = append(messages, fmt.Sprintf("%s: %v", name, id))
+	}
+	if remaining := count - int64(len(ids)); remaining > 0 {
+		messages = append(messages, fmt.Sprintf("%s: and %d more", name, remaining))
+	}
+
+	return messages
+}
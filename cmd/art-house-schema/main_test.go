@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFlags(t *testing.T) {
+	require.NoError(t, validateFlags("strict", "warn", 0))
+	require.Equal(t, errors.New(`invalid level "off", must be strict or moderate`), validateFlags("off", "error", 20))
+	require.Equal(t, errors.New(`invalid action "log", must be error or warn`), validateFlags("moderate", "log", 20))
+	require.Equal(t, errors.New("invalid limit -1, must not be negative"), validateFlags("moderate", "error", -1))
+}
+
+func TestReport(t *testing.T) {
+	require.Equal(t, []string{"artworks: no documents violate the schema"}, report("artworks", 0, []interface{}{}))
+	require.Equal(t, []string{
+		"artworks: 3 documents violate the schema",
+		"artworks: 1",
+		"artworks: and 2 more",
+	}, report("artworks", 3, []interface{}{1}))
+}
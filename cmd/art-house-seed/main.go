@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/iamnotrodger/art-house-api/cmd/config"
+	"github.com/iamnotrodger/art-house-api/internal/artist"
+	"github.com/iamnotrodger/art-house-api/internal/artwork"
+	"github.com/iamnotrodger/art-house-api/internal/exhibition"
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func main() {
+	dir := flag.String("dir", "./seed", "directory holding artists.json, artworks.json and exhibitions.json")
+	flag.Parse()
+
+	config.LoadConfig()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	client, err := util.GetMongoClient(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+	db := client.Database(config.Global.MongoDBName)
+
+	var artists []*model.Artist
+	var artworks []*model.Artwork
+	var exhibitions []*model.Exhibition
+	if err = readSeed(filepath.Join(*dir, "artists.json"), &artists); err != nil {
+		log.Fatal(err)
+	}
+	if err = readSeed(filepath.Join(*dir, "artworks.json"), &artworks); err != nil {
+		log.Fatal(err)
+	}
+	if err = readSeed(filepath.Join(*dir, "exhibitions.json"), &exhibitions); err != nil {
+		log.Fatal(err)
+	}
+
+	res, err := artist.NewStore(db).UpsertMany(ctx, artists)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report("artists", res)
+
+	artistIDs, err := findIDs(ctx, db.Collection("artists"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	artworks, unresolved := resolveArtworks(artworks, artistIDs)
+	for _, message := range unresolved {
+		log.Println(message)
+	}
+	res, err = artwork.NewStore(db).UpsertMany(ctx, artworks)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report("artworks", res)
+
+	artworkIDs, err := findIDs(ctx, db.Collection("artworks"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	unresolved = resolveExhibitions(exhibitions, artistIDs, artworkIDs)
+	for _, message := range unresolved {
+		log.Println(message)
+	}
+	res, err = exhibition.NewStore(db).UpsertMany(ctx, exhibitions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report("exhibitions", res)
+}
+
+func readSeed(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// findIDs returns the IDs of every document in the collection
+func findIDs(ctx context.Context, collection *mongo.Collection) (map[primitive.ObjectID]bool, error) {
+	values, err := collection.Distinct(ctx, "_id", bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[primitive.ObjectID]bool{}
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids[id] = true
+		}
+	}
+	return ids, nil
+}
+
+// resolveArtworks drops the artworks whose artist does not exist and describes each of them
+func resolveArtworks(artworks []*model.Artwork, artistIDs map[primitive.ObjectID]bool) ([]*model.Artwork, []string) {
+	resolved := []*model.Artwork{}
+	unresolved := []string{}
+
+	for _, art := range artworks {
+		if art.Artist == nil {
+			unresolved = append(unresolved, fmt.Sprintf("skipping artwork %q: no artist", art.Title))
+		} else if !artistIDs[art.Artist.ID] {
+			unresolved = append(unresolved, fmt.Sprintf("skipping artwork %q: unknown artist %s", art.Title, art.Artist.ID.Hex()))
+		} else {
+			resolved = append(resolved, art)
+		}
+	}
+
+	return resolved, unresolved
+}
+
+// resolveExhibitions drops the artists and artworks that do not exist from the exhibitions and describes each of them
+func resolveExhibitions(exhibitions []*model.Exhibition, artistIDs map[primitive.ObjectID]bool, artworkIDs map[primitive.ObjectID]bool) []string {
+	unresolved := []string{}
+
+	for _, exhibit := range exhibitions {
+		artists := []*model.Artist{}
+		for _, ref := range exhibit.Artists {
+			if artistIDs[ref.ID] {
+				artists = append(artists, ref)
+			} else {
+				unresolved = append(unresolved, fmt.Sprintf("exhibition %q: dropping unknown artist %s", exhibit.Name, ref.ID.Hex()))
+			}
+		}
+
+		artworks := []*model.Artwork{}
+		for _, ref := range exhibit.Artworks {
+			if artworkIDs[ref.ID] {
+				artworks = append(artworks, ref)
+			} else {
+				unresolved = append(unresolved, fmt.Sprintf("exhibition %q: dropping unknown artwork %s", exhibit.Name, ref.ID.Hex()))
+			}
+		}
+
+		exhibit.Artists = artists
+		exhibit.Artworks = artworks
+	}
+
+	return unresolved
+}
+
+func report(collection string, res *mongo.BulkWriteResult) {
+	log.Printf("%s: %d inserted, %d updated, %d unchanged\n",
+		collection, res.UpsertedCount, res.ModifiedCount, res.MatchedCount-res.ModifiedCount)
+}
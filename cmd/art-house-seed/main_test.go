@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReadSeed(t *testing.T) {
+	var artworks []*model.Artwork
+	err := readSeed("../../seed/artworks.json", &artworks)
+	require.NoError(t, err)
+	require.NotEmpty(t, artworks)
+
+	var exhibitions []*model.Exhibition
+	err = readSeed("../../seed/exhibitions.json", &exhibitions)
+	require.NoError(t, err)
+	require.NotEmpty(t, exhibitions[0].Artworks)
+}
+
+func TestResolveArtworks(t *testing.T) {
+	knownArtist := &model.Artist{ID: primitive.NewObjectID()}
+	unknownArtist := &model.Artist{ID: primitive.NewObjectID()}
+	artistIDs := map[primitive.ObjectID]bool{knownArtist.ID: true}
+
+	resolvedArtwork := &model.Artwork{Title: "resolved", Artist: knownArtist}
+	artworks := []*model.Artwork{
+		resolvedArtwork,
+		{Title: "unknown", Artist: unknownArtist},
+		{Title: "missing"},
+	}
+
+	resolved, unresolved := resolveArtworks(artworks, artistIDs)
+	require.Equal(t, []*model.Artwork{resolvedArtwork}, resolved)
+	require.Equal(t, []string{
+		"skipping artwork \"unknown\": unknown artist " + unknownArtist.ID.Hex(),
+		"skipping artwork \"missing\": no artist",
+	}, unresolved)
+}
+
+func TestResolveExhibitions(t *testing.T) {
+	knownArtist := &model.Artist{ID: primitive.NewObjectID()}
+	unknownArtist := &model.Artist{ID: primitive.NewObjectID()}
+	knownArtwork := &model.Artwork{ID: primitive.NewObjectID()}
+	unknownArtwork := &model.Artwork{ID: primitive.NewObjectID()}
+
+	exhibitions := []*model.Exhibition{
+		{
+			Name:     "name",
+			Artists:  []*model.Artist{knownArtist, unknownArtist},
+			Artworks: []*model.Artwork{unknownArtwork, knownArtwork},
+		},
+	}
+
+	unresolved := resolveExhibitions(exhibitions,
+		map[primitive.ObjectID]bool{knownArtist.ID: true},
+		map[primitive.ObjectID]bool{knownArtwork.ID: true},
+	)
+	require.Equal(t, []*model.Artist{knownArtist}, exhibitions[0].Artists)
+	require.Equal(t, []*model.Artwork{knownArtwork}, exhibitions[0].Artworks)
+	require.Equal(t, []string{
+		"exhibition \"name\": dropping unknown artist " + unknownArtist.ID.Hex(),
+		"exhibition \"name\": dropping unknown artwork " + unknownArtwork.ID.Hex(),
+	}, unresolved)
+}
@@ -23,6 +23,13 @@ const (
 	defaultExhibitionLimit    = int64(15)
 	defaultExhibitionLimitMin = int64(1)
 	defaultExhibitionLimitMax = int64(100)
+	defaultSearchLimit        = int64(15)
+	defaultSearchLimitMin     = int64(1)
+	defaultSearchLimitMax     = int64(100)
+	defaultSuggestLimit       = int64(10)
+	defaultSuggestLimitMin    = int64(1)
+	defaultSuggestLimitMax    = int64(25)
+	defaultStrictParameters   = false
 )
 
 type Spec struct {
@@ -41,6 +48,13 @@ type Spec struct {
 	ExhibitionLimit    int64  `mapstructure:"exhibition_limit"`
 	ExhibitionLimitMin int64  `mapstructure:"exhibition_limit_min"`
 	ExhibitionLimitMax int64  `mapstructure:"exhibition_limit_max"`
+	SearchLimit        int64  `mapstructure:"search_limit"`
+	SearchLimitMin     int64  `mapstructure:"search_limit_min"`
+	SearchLimitMax     int64  `mapstructure:"search_limit_max"`
+	SuggestLimit       int64  `mapstructure:"suggest_limit"`
+	SuggestLimitMin    int64  `mapstructure:"suggest_limit_min"`
+	SuggestLimitMax    int64  `mapstructure:"suggest_limit_max"`
+	StrictParameters   bool   `mapstructure:"strict_parameters"`
 }
 
 var Global = Spec{
@@ -59,6 +73,13 @@ var Global = Spec{
 	ExhibitionLimit:    defaultExhibitionLimit,
 	ExhibitionLimitMin: defaultExhibitionLimitMin,
 	ExhibitionLimitMax: defaultExhibitionLimitMax,
+	SearchLimit:        defaultSearchLimit,
+	SearchLimitMin:     defaultSearchLimitMin,
+	SearchLimitMax:     defaultSearchLimitMax,
+	SuggestLimit:       defaultSuggestLimit,
+	SuggestLimitMin:    defaultSuggestLimitMin,
+	SuggestLimitMax:    defaultSuggestLimitMax,
+	StrictParameters:   defaultStrictParameters,
 }
 
 func LoadConfig() {
@@ -26,4 +26,14 @@ func TestGlobal(t *testing.T) {
 	assert.Equal(t, Global.ExhibitionLimit, defaultExhibitionLimit)
 	assert.Equal(t, Global.ExhibitionLimitMin, defaultExhibitionLimitMin)
 	assert.Equal(t, Global.ExhibitionLimitMax, defaultExhibitionLimitMax)
+
+	assert.Equal(t, Global.SearchLimit, defaultSearchLimit)
+	assert.Equal(t, Global.SearchLimitMin, defaultSearchLimitMin)
+	assert.Equal(t, Global.SearchLimitMax, defaultSearchLimitMax)
+
+	assert.Equal(t, Global.SuggestLimit, defaultSuggestLimit)
+	assert.Equal(t, Global.SuggestLimitMin, defaultSuggestLimitMin)
+	assert.Equal(t, Global.SuggestLimitMax, defaultSuggestLimitMax)
+
+	assert.Equal(t, Global.StrictParameters, defaultStrictParameters)
 }
@@ -7,7 +7,7 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v9"
-	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/util"
 )
 
 type Cache struct {
@@ -24,44 +24,61 @@ func NewCache(client *redis.Client, expiration time.Duration) *Cache {
 	}
 }
 
-func (c *Cache) Get(ctx context.Context, artistID string) (*model.Artist, error) {
-	key := c.getKeyByID(artistID)
-	val, err := c.client.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
+func (c *Cache) Get(ctx context.Context, artistID string) (*util.Response, error) {
+	return c.get(ctx, c.getKeyByID(artistID))
+}
 
-	var artist model.Artist
-	err = json.Unmarshal([]byte(val), &artist)
-	if err != nil {
-		return nil, err
-	}
+func (c *Cache) GetMany(ctx context.Context, queryString string) (*util.Response, error) {
+	return c.get(ctx, c.getKeyByQuery(queryString))
+}
 
-	return &artist, nil
+func (c *Cache) GetArtworks(ctx context.Context, artistID string, queryString string) (*util.Response, error) {
+	return c.get(ctx, c.getKeyByArtworks(artistID, queryString))
 }
 
-func (c *Cache) GetMany(ctx context.Context, queryString string) ([]*model.Artist, error) {
-	key := c.getKeyByQuery(queryString)
-	val, err := c.client.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
+func (c *Cache) Set(ctx context.Context, artistID string, res *util.Response) error {
+	return c.set(ctx, c.getKeyByID(artistID), res)
+}
 
-	var artists []*model.Artist
-	err = json.Unmarshal([]byte(val), &artists)
-	if err != nil {
-		return nil, err
+func (c *Cache) SetMany(ctx context.Context, queryString string, res *util.Response) error {
+	return c.set(ctx, c.getKeyByQuery(queryString), res)
+}
+
+func (c *Cache) SetArtworks(ctx context.Context, artistID string, queryString string, res *util.Response) error {
+	return c.set(ctx, c.getKeyByArtworks(artistID, queryString), res)
+}
+
+// Invalidate removes the cached artist, the artist's cached artworks and every cached artist listing
+func (c *Cache) Invalidate(ctx context.Context, artistID string) error {
+	keys := []string{c.getKeyByID(artistID)}
+	return c.invalidate(ctx, keys, c.getKeyPatternByQuery(), c.getKeyPatternByArtworks(artistID))
+}
+
+// InvalidateArtworks removes the cached artworks of every artist, an artwork
+// write can move an artwork from one artist to another
+func (c *Cache) InvalidateArtworks(ctx context.Context) error {
+	return c.invalidate(ctx, nil, c.getKeyPatternByArtworks("*"))
+}
+
+// invalidate removes the keys and every key matching one of the patterns
+func (c *Cache) invalidate(ctx context.Context, keys []string, patterns ...string) error {
+	for _, pattern := range patterns {
+		iter := c.client.Scan(ctx, 0, pattern, 0).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
 	}
 
-	return artists, nil
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.client.Del(ctx, keys...).Err()
 }
 
-func (c *Cache) GetArtworks(ctx context.Context, artistID string, queryString string) ([]*model.Artwork, error) {
-	key := c.getKeyByArtworks(artistID, queryString)
+func (c *Cache) get(ctx context.Context, key string) (*util.Response, error) {
 	val, err := c.client.Get(ctx, key).Result()
 	if err == redis.Nil {
 		return nil, nil
@@ -69,46 +86,23 @@ func (c *Cache) GetArtworks(ctx context.Context, artistID string, queryString st
 		return nil, err
 	}
 
-	var artworks []*model.Artwork
-	err = json.Unmarshal([]byte(val), &artworks)
+	var res util.Response
+	err = json.Unmarshal([]byte(val), &res)
 	if err != nil {
 		return nil, err
 	}
 
-	return artworks, nil
-}
-
-func (c *Cache) Set(ctx context.Context, artistID string, artist *model.Artist) error {
-	artistJson, err := json.Marshal(artist)
-	if err != nil {
-		return err
-	}
-
-	key := c.getKeyByID(artistID)
-	err = c.client.Set(ctx, key, artistJson, c.expiration).Err()
-	return err
+	return &res, nil
 }
 
-func (c *Cache) SetMany(ctx context.Context, queryString string, artists []*model.Artist) error {
-	artistsJson, err := json.Marshal(artists)
+// set caches the response body along with its ETag
+func (c *Cache) set(ctx context.Context, key string, res *util.Response) error {
+	resJson, err := json.Marshal(res)
 	if err != nil {
 		return err
 	}
 
-	key := c.getKeyByQuery(queryString)
-	err = c.client.Set(ctx, key, artistsJson, c.expiration).Err()
-	return err
-}
-
-func (c *Cache) SetArtworks(ctx context.Context, artistID string, queryString string, artworks []*model.Artwork) error {
-	artworksJson, err := json.Marshal(artworks)
-	if err != nil {
-		return err
-	}
-
-	key := c.getKeyByArtworks(artistID, queryString)
-	err = c.client.Set(ctx, key, artworksJson, c.expiration).Err()
-	return err
+	return c.client.Set(ctx, key, resJson, c.expiration).Err()
 }
 
 func (c *Cache) getKeyByID(artistID string) string {
@@ -122,3 +116,11 @@ func (c *Cache) getKeyByQuery(queryString string) string {
 func (c *Cache) getKeyByArtworks(artistID string, queryString string) string {
 	return fmt.Sprintf("%s:%s:%s?%s", c.namespace, artistID, "artwork", queryString)
 }
+
+func (c *Cache) getKeyPatternByQuery() string {
+	return fmt.Sprintf("%s\\?*", c.namespace)
+}
+
+func (c *Cache) getKeyPatternByArtworks(artistID string) string {
+	return fmt.Sprintf("%s:%s:%s\\?*", c.namespace, artistID, "artwork")
+}
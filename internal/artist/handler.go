@@ -5,29 +5,56 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/iamnotrodger/art-house-api/internal/model"
 	"github.com/iamnotrodger/art-house-api/internal/query"
+	"github.com/iamnotrodger/art-house-api/internal/revision"
 	"github.com/iamnotrodger/art-house-api/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
+// ArtworkCache is the cache of the artworks, which are cached with their artist
+type ArtworkCache interface {
+	InvalidateAll(ctx context.Context) error
+}
+
+// ExhibitionCache is the cache of the exhibitions, which are cached with their
+// artists and artworks
+type ExhibitionCache interface {
+	InvalidateContents(ctx context.Context) error
+	InvalidateAll(ctx context.Context) error
+}
+
 type Handler struct {
-	store *Store
-	cache *Cache
+	store           *Store
+	cache           *Cache
+	artworkCache    ArtworkCache
+	exhibitionCache ExhibitionCache
+	revisions       *revision.Store
 }
 
-func NewHandler(store *Store, cache *Cache) *Handler {
+func NewHandler(store *Store, cache *Cache, artworkCache ArtworkCache, exhibitionCache ExhibitionCache, revisions *revision.Store) *Handler {
 	return &Handler{
-		store: store,
-		cache: cache,
+		store:           store,
+		cache:           cache,
+		artworkCache:    artworkCache,
+		exhibitionCache: exhibitionCache,
+		revisions:       revisions,
 	}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/artists", h.GetMany).Methods("GET")
+	router.HandleFunc("/api/artists", h.Create).Methods("POST")
 	router.HandleFunc("/api/artists/{id}", h.Get).Methods("GET")
+	router.HandleFunc("/api/artists/{id}", h.Update).Methods("PUT")
+	router.HandleFunc("/api/artists/{id}", h.Delete).Methods("DELETE")
 	router.HandleFunc("/api/artists/{id}/artworks", h.GetArtworks).Methods("GET")
+	router.HandleFunc("/api/admin/trash/artists", h.GetDeleted).Methods("GET")
+	router.HandleFunc("/api/admin/trash/artists/{id}/restore", h.Restore).Methods("POST")
+	router.HandleFunc("/api/admin/trash/artists/{id}", h.Purge).Methods("DELETE")
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
@@ -36,39 +63,53 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	artistID := params["id"]
 
-	artist, err := h.getOrSetArtistCache(r.Context(), artistID)
+	res, err := h.getOrSetArtistCache(r.Context(), artistID)
 	if err != nil {
 		util.HandleError(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(artist)
+	res.Write(w, r, http.StatusOK)
 }
 
 func (h *Handler) GetMany(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	queryParams, err := query.NewArtistQuery(r.URL.Query())
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, queryParams); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
 	queryString := r.URL.RawQuery
-	artists, err := h.cache.GetMany(r.Context(), queryString)
+	res, err := h.cache.GetMany(r.Context(), queryString)
 	if err != nil {
 		log.Println(err)
-	} else if artists != nil {
-		json.NewEncoder(w).Encode(artists)
+	} else if res != nil {
+		res.Write(w, r, http.StatusOK)
 		return
 	}
 
-	queryParams := query.NewArtistQuery(r.URL.Query())
-	artists, err = h.store.FindMany(r.Context(), queryParams)
+	artists, page, err := h.store.FindMany(r.Context(), queryParams)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	res, err = util.NewPageResponse(r, artists, page)
 	if err != nil {
 		util.HandleError(w, err)
 		return
 	}
-	err = h.cache.SetMany(r.Context(), queryString, artists)
+	err = h.cache.SetMany(r.Context(), queryString, res)
 	if err != nil {
 		log.Println(err)
 	}
 
-	json.NewEncoder(w).Encode(artists)
+	res.Write(w, r, http.StatusOK)
 }
 
 func (h *Handler) GetArtworks(w http.ResponseWriter, r *http.Request) {
@@ -77,56 +118,303 @@ func (h *Handler) GetArtworks(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	artistID := params["id"]
 
+	queryParams, err := query.NewArtworkQuery(r.URL.Query())
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, queryParams); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
 	queryString := r.URL.RawQuery
-	artworks, err := h.cache.GetArtworks(r.Context(), artistID, queryString)
+	res, err := h.cache.GetArtworks(r.Context(), artistID, queryString)
 	if err != nil {
 		log.Println(err)
-	} else if artworks != nil {
-		json.NewEncoder(w).Encode(artworks)
+	} else if res != nil {
+		res.Write(w, r, http.StatusOK)
 		return
 	}
 
-	queryParams := query.NewArtworkQuery(r.URL.Query())
-	artworks, err = h.store.FindArtworks(r.Context(), artistID, queryParams)
+	artworks, page, err := h.store.FindArtworks(r.Context(), artistID, queryParams)
 	if err != nil {
 		util.HandleError(w, err)
 		return
 	}
 
-	artist, err := h.getOrSetArtistCache(r.Context(), artistID)
+	artistRes, err := h.getOrSetArtistCache(r.Context(), artistID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	var artist model.Artist
+	err = json.Unmarshal(artistRes.Body, &artist)
 	if err != nil {
 		util.HandleError(w, err)
 		return
 	}
 
-	for _, artwork := range artworks {
-		artwork.Artist = artist
+	// a projection already joined the selected fields of the artist
+	if len(queryParams.GetProjection()) == 0 {
+		for _, artwork := range artworks {
+			artwork.Artist = &artist
+		}
 	}
 
-	err = h.cache.SetArtworks(r.Context(), artistID, queryString, artworks)
+	res, err = util.NewPageResponse(r, artworks, page)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	err = h.cache.SetArtworks(r.Context(), artistID, queryString, res)
 	if err != nil {
 		log.Println(err)
 	}
 
-	json.NewEncoder(w).Encode(artworks)
+	res.Write(w, r, http.StatusOK)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var artist model.Artist
+	err := json.NewDecoder(r.Body).Decode(&artist)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	err = artist.Validate()
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	doc, err := h.store.InsertOne(r.Context(), &artist)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionCreate)
+
+	h.respondWithArtist(w, r, artist.ID.Hex(), http.StatusCreated)
 }
 
-func (h *Handler) getOrSetArtistCache(ctx context.Context, artistID string) (*model.Artist, error) {
-	artist, err := h.cache.Get(ctx, artistID)
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	artistID := params["id"]
+
+	version, err := util.GetIfMatch(r, func() (int64, error) {
+		return h.store.Version(r.Context(), artistID)
+	})
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	var artist model.Artist
+	err = json.NewDecoder(r.Body).Decode(&artist)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	err = artist.Validate()
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	doc, err := h.store.Update(r.Context(), artistID, &artist, version)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionUpdate)
+	h.invalidateRelated(r.Context(), false)
+
+	h.respondWithArtist(w, r, artistID, http.StatusOK)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	artistID := params["id"]
+
+	cascade := false
+	if cascadeString := r.URL.Query().Get("cascade"); cascadeString != "" {
+		var err error
+		cascade, err = strconv.ParseBool(cascadeString)
+		if err != nil {
+			util.HandleError(w, &model.ParameterError{Parameter: "cascade", Message: "must be true or false"})
+			return
+		}
+	}
+
+	doc, artworks, err := h.store.Delete(r.Context(), artistID, cascade)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionDelete)
+	h.recordArtworkRevisions(r, artworks, model.RevisionDelete)
+	if cascade {
+		h.invalidateRelated(r.Context(), false)
+	}
+
+	err = h.cache.Invalidate(r.Context(), artistID)
 	if err != nil {
 		log.Println(err)
-	} else if artist != nil {
-		return artist, nil
 	}
 
-	artist, err = h.store.Find(ctx, artistID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetDeleted(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	queryParams, err := query.NewArtistQuery(r.URL.Query(), query.DeletedSort)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, queryParams); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	artists, page, err := h.store.FindDeleted(r.Context(), queryParams)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	util.RespondWithPage(w, r, http.StatusOK, artists, page)
+}
+
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	artistID := params["id"]
+
+	doc, artworks, err := h.store.Restore(r.Context(), artistID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionRestore)
+	h.recordArtworkRevisions(r, artworks, model.RevisionRestore)
+	h.invalidateRelated(r.Context(), false)
+
+	h.respondWithArtist(w, r, artistID, http.StatusOK)
+}
+
+func (h *Handler) Purge(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	artistID := params["id"]
+
+	doc, err := h.store.Purge(r.Context(), artistID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionPurge)
+
+	err = h.cache.Invalidate(r.Context(), artistID)
+	if err != nil {
+		log.Println(err)
+	}
+	h.invalidateRelated(r.Context(), true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithArtist invalidates the cached artist after a write and responds with its stored state
+func (h *Handler) respondWithArtist(w http.ResponseWriter, r *http.Request, artistID string, statusCode int) {
+	err := h.cache.Invalidate(r.Context(), artistID)
+	if err != nil {
+		log.Println(err)
+	}
+
+	res, err := h.getOrSetArtistCache(r.Context(), artistID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	res.Write(w, r, statusCode)
+}
+
+func (h *Handler) getOrSetArtistCache(ctx context.Context, artistID string) (*util.Response, error) {
+	res, err := h.cache.Get(ctx, artistID)
+	if err != nil {
+		log.Println(err)
+	} else if res != nil {
+		return res, nil
+	}
+
+	artist, err := h.store.Find(ctx, artistID)
+	if err != nil {
+		return nil, err
+	}
+	res, err = util.NewVersionedResponse(artist, artist.Version)
 	if err != nil {
 		return nil, err
 	}
-	err = h.cache.Set(ctx, artistID, artist)
+	err = h.cache.Set(ctx, artistID, res)
+	if err != nil {
+		log.Println(err)
+	}
+
+	return res, nil
+}
+
+// SyncReverted removes the cached entries that hold the artist after it is reverted
+func (h *Handler) SyncReverted(ctx context.Context, artistID string) error {
+	err := h.cache.Invalidate(ctx, artistID)
 	if err != nil {
 		log.Println(err)
 	}
+	h.invalidateRelated(ctx, false)
 
-	return artist, nil
+	return nil
+}
+
+// invalidateRelated removes the cached artworks and exhibitions that a write to
+// an artist makes stale. The artworks are cached with their artist, and the
+// exhibitions with their artworks and artists. inExhibitions is set when the
+// write took artworks or the artist out of the exhibitions themselves.
+func (h *Handler) invalidateRelated(ctx context.Context, inExhibitions bool) {
+	err := h.artworkCache.InvalidateAll(ctx)
+	if err != nil {
+		log.Println(err)
+	}
+
+	if inExhibitions {
+		err = h.exhibitionCache.InvalidateAll(ctx)
+	} else {
+		err = h.exhibitionCache.InvalidateContents(ctx)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// recordRevision snapshots the artist as a write left it, a failure does not fail the write
+func (h *Handler) recordRevision(r *http.Request, document bson.M, action string) {
+	_, err := h.revisions.Record(r.Context(), "artists", action, revision.GetAuthor(r), document)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// recordArtworkRevisions snapshots the artworks that a write to the artist
+// cascaded to, as an artwork's own write would
+func (h *Handler) recordArtworkRevisions(r *http.Request, documents []bson.M, action string) {
+	for _, document := range documents {
+		_, err := h.revisions.Record(r.Context(), "artworks", action, revision.GetAuthor(r), document)
+		if err != nil {
+			log.Println(err)
+		}
+	}
 }
@@ -3,9 +3,11 @@ package artist
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/iamnotrodger/art-house-api/internal/model"
 	"github.com/iamnotrodger/art-house-api/internal/query"
+	"github.com/iamnotrodger/art-house-api/internal/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -27,11 +29,13 @@ func NewStore(db *mongo.Database) *Store {
 func (s *Store) Find(ctx context.Context, artistID string) (*model.Artist, error) {
 	id, err := primitive.ObjectIDFromHex(artistID)
 	if err != nil {
-		return nil, primitive.ErrInvalidHex
+		return nil, model.ErrInvalidID
 	}
 
-	singleRes := s.collection.FindOne(ctx, bson.M{"_id": id}, &options.FindOneOptions{})
-	if err = singleRes.Err(); err != nil {
+	singleRes := s.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": nil}, &options.FindOneOptions{})
+	if err = singleRes.Err(); err == mongo.ErrNoDocuments {
+		return nil, model.ErrNotFound
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -46,9 +50,9 @@ func (s *Store) Find(ctx context.Context, artistID string) (*model.Artist, error
 	return artist, nil
 }
 
-func (s *Store) FindMany(ctx context.Context, queryParam ...query.QueryParams) ([]*model.Artist, error) {
+func (s *Store) FindMany(ctx context.Context, queryParam ...query.QueryParams) ([]*model.Artist, *model.Page, error) {
 	var opts *options.FindOptions
-	filter := bson.D{}
+	filter := query.NotDeletedFilter
 
 	if len(queryParam) > 0 {
 		filter = queryParam[0].GetFilter()
@@ -57,58 +61,102 @@ func (s *Store) FindMany(ctx context.Context, queryParam ...query.QueryParams) (
 
 	cursor, err := s.collection.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer cursor.Close(ctx)
 
-	artists := []*model.Artist{}
-	err = cursor.All(ctx, &artists)
+	artists, last, err := decodeArtists(ctx, cursor)
 	if err != nil {
-		err = fmt.Errorf("failed to unmarshal artists: %w", err)
-		return nil, err
+		return nil, nil, err
+	}
+	var total int64
+	if len(queryParam) > 0 {
+		total, err = query.Count(ctx, s.collection, queryParam[0].GetCountPipeline())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	page, err := query.NewPage(len(artists), last, total, queryParam...)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	for _, artist := range artists {
 		model.SortImages(artist.Images)
 	}
 
-	return artists, nil
+	return artists, page, nil
 }
 
-func (s *Store) FindArtworks(ctx context.Context, artistID string, queryParam ...query.QueryParams) ([]*model.Artwork, error) {
+func (s *Store) FindArtworks(ctx context.Context, artistID string, queryParam ...query.QueryParams) ([]*model.Artwork, *model.Page, error) {
 	id, err := primitive.ObjectIDFromHex(artistID)
 	if err != nil {
-		return nil, primitive.ErrInvalidHex
+		return nil, nil, model.ErrInvalidID
 	}
 
-	var opts *options.FindOptions
-	filter := bson.D{{Key: "artist_id", Value: id}}
+	err = query.FindExhibitionArtworks(ctx, s.db, queryParam...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byArtist := bson.D{{Key: "$match", Value: bson.D{{Key: "artist_id", Value: id}}}}
+	withoutArtist := bson.D{{Key: "$project", Value: bson.D{{Key: "artist", Value: 0}}}}
+
+	pipeline := mongo.Pipeline{byArtist, query.NotDeletedStage}
 	if len(queryParam) > 0 {
-		filter = append(filter, queryParam[0].GetFilter()...)
-		opts = queryParam[0].GetFindOptions()
-	} else {
-		opts = options.Find()
+		pipeline = matchArtist(byArtist, queryParam[0].GetPipeline())
+	}
+	// artworks keep their artist only when the query joined it to sort or filter on it
+	if !query.JoinsArtist(pipeline) {
+		pipeline = append(pipeline, withoutArtist)
 	}
-	opts.SetProjection(bson.M{"artist": 0})
 
-	cursor, err := s.db.Collection("artworks").Find(ctx, filter, opts)
+	artworksCollection := s.db.Collection("artworks")
+	cursor, err := artworksCollection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer cursor.Close(ctx)
 
+	var last bson.Raw
 	artworks := []*model.Artwork{}
-	err = cursor.All(ctx, &artworks)
+	for cursor.Next(ctx) {
+		var artwork model.Artwork
+		if err = cursor.Decode(&artwork); err != nil {
+			err = fmt.Errorf("failed to unmarshal artworks: %w", err)
+			return nil, nil, err
+		}
+		artworks = append(artworks, &artwork)
+		last = cursor.Current
+	}
+	if err = cursor.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var total int64
+	if len(queryParam) > 0 {
+		total, err = query.Count(ctx, artworksCollection, matchArtist(byArtist, queryParam[0].GetCountPipeline()))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	page, err := query.NewPage(len(artworks), last, total, queryParam...)
 	if err != nil {
-		err = fmt.Errorf("failed to unmarshal artworks: %w", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	for _, artwork := range artworks {
 		model.SortImages(artwork.Images)
 	}
 
-	return artworks, nil
+	return artworks, page, nil
+}
+
+// matchArtist narrows the pipeline of a query down to the artworks of the artist,
+// right after the query's own $match which has to come first for a $text search
+func matchArtist(byArtist bson.D, stages []bson.D) mongo.Pipeline {
+	pipeline := mongo.Pipeline{stages[0], byArtist}
+	return append(pipeline, stages[1:]...)
 }
 
 func (s *Store) InsertMany(ctx context.Context, artists []*model.Artist) error {
@@ -122,3 +170,377 @@ func (s *Store) InsertMany(ctx context.Context, artists []*model.Artist) error {
 	_, err := s.collection.InsertMany(ctx, docs)
 	return err
 }
+
+// Stream decodes every artist one at a time from the cursor and passes it to fn
+func (s *Store) Stream(ctx context.Context, fn func(*model.Artist) error) error {
+	cursor, err := s.collection.Find(ctx, query.NotDeletedFilter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var artist model.Artist
+		err = cursor.Decode(&artist)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal artist: %w", err)
+		}
+
+		model.SortImages(artist.Images)
+		if err = fn(&artist); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// UpsertMany replaces the artists matching by ID, or by name when an artist
+// has no ID, and inserts the ones that do not exist yet
+func (s *Store) UpsertMany(ctx context.Context, artists []*model.Artist) (*mongo.BulkWriteResult, error) {
+	writes := []mongo.WriteModel{}
+
+	for _, artist := range artists {
+		filter := bson.M{"_id": artist.ID}
+		if artist.ID.IsZero() {
+			filter = bson.M{"name": artist.Name}
+		}
+
+		model.SortImages(artist.Images)
+		write := mongo.NewReplaceOneModel().
+			SetFilter(filter).
+			SetReplacement(artist.ConvertToBson()).
+			SetUpsert(true)
+		writes = append(writes, write)
+	}
+
+	if len(writes) == 0 {
+		return &mongo.BulkWriteResult{}, nil
+	}
+	return s.collection.BulkWrite(ctx, writes)
+}
+
+// InsertOne stores the artist and returns it as it was stored
+func (s *Store) InsertOne(ctx context.Context, artist *model.Artist) (bson.M, error) {
+	if artist.ID.IsZero() {
+		artist.ID = primitive.NewObjectID()
+	}
+	artist.Version = 1
+	model.SortImages(artist.Images)
+
+	doc := artist.ConvertToBson()
+	_, err := s.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	return util.StoredDocument(doc)
+}
+
+// Update replaces the artist if it is still stored at version, and returns it
+// as it was stored
+func (s *Store) Update(ctx context.Context, artistID string, artist *model.Artist, version int64) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(artistID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	artist.ID = id
+	artist.Version = version + 1
+	model.SortImages(artist.Images)
+
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "deleted_at", Value: nil}, query.VersionFilter(version)}
+	doc, err := util.ReplaceAndReturn(ctx, s.collection, filter, artist.ConvertToBson())
+	if err == mongo.ErrNoDocuments {
+		return nil, s.modifiedError(ctx, id)
+	}
+	return doc, err
+}
+
+// Version returns the version the artist is stored at
+func (s *Store) Version(ctx context.Context, artistID string) (int64, error) {
+	return util.FindVersion(ctx, s.collection, artistID)
+}
+
+// Delete moves the artist to the trash. An artist that is still referenced by
+// artworks or exhibitions is only deleted when cascade is set, in which case the
+// artist's artworks are moved to the trash with it in one transaction. The
+// exhibitions keep them until they are purged, and hide them while they are in
+// the trash. It returns the artist and the artworks deleted with it as they
+// were stored.
+func (s *Store) Delete(ctx context.Context, artistID string, cascade bool) (bson.M, []bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(artistID)
+	if err != nil {
+		return nil, nil, model.ErrInvalidID
+	}
+
+	artworks := s.db.Collection("artworks")
+	exhibitions := s.db.Collection("exhibitions")
+	artworksFilter := bson.M{"artist_id": id, "deleted_at": nil}
+	exhibitionsFilter := bson.M{"artist_ids": id, "deleted_at": nil}
+	update := bson.M{"$set": bson.M{"deleted_at": time.Now()}, "$inc": bson.M{"version": 1}}
+
+	if !cascade {
+		artworkCount, err := artworks.CountDocuments(ctx, artworksFilter)
+		if err != nil {
+			return nil, nil, err
+		}
+		exhibitionCount, err := exhibitions.CountDocuments(ctx, exhibitionsFilter)
+		if err != nil {
+			return nil, nil, err
+		}
+		if artworkCount > 0 || exhibitionCount > 0 {
+			message := fmt.Sprintf("artist is referenced by %d artworks and %d exhibitions", artworkCount, exhibitionCount)
+			return nil, nil, &model.ConflictError{Message: message}
+		}
+		doc, err := s.trash(ctx, id, update)
+		return doc, nil, err
+	}
+
+	var doc bson.M
+	var deleted []bson.M
+	err = util.WithTransaction(ctx, s.db.Client(), func(sessCtx mongo.SessionContext) error {
+		doc, err = s.trash(sessCtx, id, update)
+		if err != nil {
+			return err
+		}
+
+		deleted, err = updateArtworks(sessCtx, artworks, artworksFilter, update)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, deleted, nil
+}
+
+// trash moves the artist to the trash with the update and returns it as it was stored
+func (s *Store) trash(ctx context.Context, id primitive.ObjectID, update bson.M) (bson.M, error) {
+	doc, err := util.UpdateAndReturn(ctx, s.collection, bson.M{"_id": id, "deleted_at": nil}, update)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrNotFound
+	}
+	return doc, err
+}
+
+// FindDeleted returns the artists in the trash, most recently deleted first
+func (s *Store) FindDeleted(ctx context.Context, queryParam ...query.QueryParams) ([]*model.Artist, *model.Page, error) {
+	opts := options.Find().SetSort(bson.D{query.DeletedSort})
+	filter := append(bson.D{}, query.DeletedFilter...)
+	if len(queryParam) > 0 {
+		opts = queryParam[0].GetFindOptions()
+		filter = append(filter, queryParam[0].GetCursorFilter()...)
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	artists, last, err := decodeArtists(ctx, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	var total int64
+	if len(queryParam) > 0 {
+		total, err = query.Count(ctx, s.collection, query.CountPipeline(query.DeletedFilter))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	page, err := query.NewPage(len(artists), last, total, queryParam...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, artist := range artists {
+		model.SortImages(artist.Images)
+	}
+
+	return artists, page, nil
+}
+
+// Restore moves the artist out of the trash along with the artworks that were
+// deleted with it, in one transaction. It returns the artist and the restored
+// artworks as they were stored.
+func (s *Store) Restore(ctx context.Context, artistID string) (bson.M, []bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(artistID)
+	if err != nil {
+		return nil, nil, model.ErrInvalidID
+	}
+
+	var doc bson.M
+	var restored []bson.M
+	err = util.WithTransaction(ctx, s.db.Client(), func(sessCtx mongo.SessionContext) error {
+		singleRes := s.collection.FindOne(sessCtx, bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}})
+		if err := singleRes.Err(); err == mongo.ErrNoDocuments {
+			return model.ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		artist := &model.Artist{}
+		err := singleRes.Decode(artist)
+		if err != nil {
+			err = fmt.Errorf("error decoding artist: %w", err)
+			return err
+		}
+
+		update := bson.M{"$unset": bson.M{"deleted_at": ""}, "$inc": bson.M{"version": 1}}
+		doc, err = util.UpdateAndReturn(sessCtx, s.collection, bson.M{"_id": id}, update)
+		if err != nil {
+			return err
+		}
+
+		artworksFilter := bson.M{"artist_id": id, "deleted_at": artist.DeletedAt}
+		restored, err = updateArtworks(sessCtx, s.db.Collection("artworks"), artworksFilter, update)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, restored, nil
+}
+
+// Purge permanently removes the artist and its artworks from the trash and from
+// the exhibitions, in one transaction. An artist that still has artworks
+// outside of the trash cannot be purged. It returns the artist as it was stored
+// before it was removed.
+func (s *Store) Purge(ctx context.Context, artistID string) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(artistID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	artworks := s.db.Collection("artworks")
+	var doc bson.M
+	err = util.WithTransaction(ctx, s.db.Client(), func(sessCtx mongo.SessionContext) error {
+		artworkCount, err := artworks.CountDocuments(sessCtx, bson.M{"artist_id": id, "deleted_at": nil})
+		if err != nil {
+			return err
+		}
+		if artworkCount > 0 {
+			message := fmt.Sprintf("artist is referenced by %d artworks", artworkCount)
+			return &model.ConflictError{Message: message}
+		}
+
+		doc, err = util.DeleteAndReturn(sessCtx, s.collection, bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}})
+		if err == mongo.ErrNoDocuments {
+			return model.ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		artworkIDs, err := artworks.Distinct(sessCtx, "_id", bson.M{"artist_id": id})
+		if err != nil {
+			return err
+		}
+		err = pullFromExhibitions(sessCtx, s.db.Collection("exhibitions"), id, artworkIDs)
+		if err != nil {
+			return err
+		}
+
+		_, err = artworks.DeleteMany(sessCtx, bson.M{"artist_id": id})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ValidateSnapshot checks a snapshot of an artist as Update checks an artist,
+// before the artist is reverted to it
+func (s *Store) ValidateSnapshot(ctx context.Context, snapshot bson.M) error {
+	raw, err := bson.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	artist := &model.Artist{}
+	err = bson.Unmarshal(raw, artist)
+	if err != nil {
+		return fmt.Errorf("error decoding artist: %w", err)
+	}
+	return artist.Validate()
+}
+
+// updateArtworks applies the update to the artworks matching the filter and
+// returns them as the update left them
+func updateArtworks(ctx context.Context, artworks *mongo.Collection, filter bson.M, update bson.M) ([]bson.M, error) {
+	ids, err := artworks.Distinct(ctx, "_id", filter)
+	if err != nil {
+		return nil, err
+	}
+	docs := []bson.M{}
+	if len(ids) == 0 {
+		return docs, nil
+	}
+
+	byID := bson.M{"_id": bson.M{"$in": ids}}
+	_, err = artworks.UpdateMany(ctx, byID, update)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := artworks.Find(ctx, byID)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	err = cursor.All(ctx, &docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding artworks: %w", err)
+	}
+	return docs, nil
+}
+
+// pullFromExhibitions takes the artist and its artworks out of every exhibition
+func pullFromExhibitions(ctx context.Context, exhibitions *mongo.Collection, id primitive.ObjectID, artworkIDs []interface{}) error {
+	if artworkIDs == nil {
+		artworkIDs = []interface{}{}
+	}
+	artworkIDsFilter := bson.M{"$in": artworkIDs}
+
+	filter := bson.M{"$or": bson.A{bson.M{"artist_ids": id}, bson.M{"artwork_ids": artworkIDsFilter}}}
+	update := bson.M{
+		"$pull": bson.M{
+			"artist_ids":  id,
+			"artwork_ids": artworkIDsFilter,
+		},
+		"$inc": bson.M{"version": 1},
+	}
+	_, err := exhibitions.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// modifiedError reports why a versioned write matched no artist
+func (s *Store) modifiedError(ctx context.Context, id primitive.ObjectID) error {
+	count, err := s.collection.CountDocuments(ctx, bson.M{"_id": id, "deleted_at": nil})
+	if err != nil {
+		return err
+	}
+	if count < 1 {
+		return model.ErrNotFound
+	}
+	return &model.PreconditionError{Message: "artist has been modified"}
+}
+
+// decodeArtists decodes the artists of the cursor and returns the last
+// document, which positions the cursor of the next page
+func decodeArtists(ctx context.Context, cursor *mongo.Cursor) ([]*model.Artist, bson.Raw, error) {
+	var last bson.Raw
+	artists := []*model.Artist{}
+	for cursor.Next(ctx) {
+		var artist model.Artist
+		if err := cursor.Decode(&artist); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal artists: %w", err)
+		}
+		artists = append(artists, &artist)
+		last = cursor.Current
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return artists, last, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/iamnotrodger/art-house-api/internal/model"
 	"github.com/stretchr/testify/require"
@@ -15,7 +16,8 @@ import (
 
 var (
 	MongoFailResponse    = bson.D{{Key: "ok", Value: 0}}
-	ErrMongoCommandError = mongo.CommandError{Message: "command failed"}
+	MongoFailRaw, _      = bson.Marshal(MongoFailResponse)
+	ErrMongoCommandError = mongo.CommandError{Message: "command failed", Raw: MongoFailRaw}
 	ErrMongoNoResponses  = mongo.CommandError{Message: "no responses remaining", Labels: []string{"NetworkError"}, Wrapped: errors.New("no responses remaining")}
 
 	artistID          = "60e0850266d6c13d7b599b69"
@@ -49,6 +51,11 @@ var (
 	}
 )
 
+// writeResponse answers a write that returns the document as it left it stored
+func writeResponse(doc bson.D) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: doc})
+}
+
 func TestFind(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -62,7 +69,7 @@ func TestFind(t *testing.T) {
 			artistID:       "invalid_ID",
 			dbResponse:     []bson.D{},
 			expectedArtist: nil,
-			expectedError:  primitive.ErrInvalidHex,
+			expectedError:  model.ErrInvalidID,
 		},
 		{
 			name:     "no artist found",
@@ -71,7 +78,7 @@ func TestFind(t *testing.T) {
 				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch),
 			},
 			expectedArtist: nil,
-			expectedError:  mongo.ErrNoDocuments,
+			expectedError:  model.ErrNotFound,
 		},
 		{
 			name:     "artist found",
@@ -177,7 +184,7 @@ func TestFindMany(t *testing.T) {
 			mt.AddMockResponses(tc.dbResponse...)
 
 			store := NewStore(mt.DB)
-			artist, err := store.FindMany(context.Background())
+			artist, _, err := store.FindMany(context.Background())
 			require.Equal(mt, tc.expectedArtists, artist)
 			require.Equal(mt, tc.expectedError, err)
 		})
@@ -200,7 +207,7 @@ func TestFindArtworks(t *testing.T) {
 			artistID:         "invalid_ID",
 			dbResponse:       []bson.D{},
 			expectedArtworks: nil,
-			expectedError:    primitive.ErrInvalidHex,
+			expectedError:    model.ErrInvalidID,
 		},
 		{
 			name:     "no artist's artwork found",
@@ -268,7 +275,7 @@ func TestFindArtworks(t *testing.T) {
 			mt.AddMockResponses(tc.dbResponse...)
 
 			store := NewStore(mt.DB)
-			artworks, err := store.FindArtworks(context.Background(), tc.artistID)
+			artworks, _, err := store.FindArtworks(context.Background(), tc.artistID)
 			require.Equal(mt, tc.expectedArtworks, artworks)
 			require.Equal(mt, tc.expectedError, err)
 		})
@@ -326,3 +333,411 @@ func TestInsertMany(t *testing.T) {
 		})
 	}
 }
+
+func TestInsertOne(t *testing.T) {
+	testCases := []struct {
+		name          string
+		artist        *model.Artist
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:   "insert artist",
+			artist: &model.Artist{Name: "name", Images: images},
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(),
+			},
+			expectedError: nil,
+		},
+		{
+			name:   "insert fails with an error",
+			artist: &model.Artist{Name: "name", Images: images},
+			dbResponse: []bson.D{
+				MongoFailResponse,
+			},
+			expectedError: ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.InsertOne(context.Background(), tc.artist)
+			require.Equal(mt, tc.expectedError, err)
+			require.False(mt, tc.artist.ID.IsZero())
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		artistID      string
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid artistID",
+			artistID:      "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:     "artist updated",
+			artistID: artistID,
+			dbResponse: []bson.D{
+				writeResponse(bson.D{{Key: "_id", Value: artistObjectID}, {Key: "version", Value: int64(2)}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "no artist found",
+			artistID: artistID,
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch),
+			},
+			expectedError: model.ErrNotFound,
+		},
+		{
+			name:     "artist modified since version",
+			artistID: artistID,
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			},
+			expectedError: &model.PreconditionError{Message: "artist has been modified"},
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.Update(context.Background(), tc.artistID, &model.Artist{Name: "name"}, 1)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	countResponse := func(n int) bson.D {
+		return mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "n", Value: n}})
+	}
+
+	artworkOne := primitive.NewObjectID()
+	artworkTwo := primitive.NewObjectID()
+
+	testCases := []struct {
+		name             string
+		artistID         string
+		cascade          bool
+		dbResponse       []bson.D
+		expectedArtworks int
+		expectedError    error
+	}{
+		{
+			name:          "invalid artistID",
+			artistID:      "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:     "unreferenced artist deleted",
+			artistID: artistID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch),
+				writeResponse(bson.D{{Key: "_id", Value: artistObjectID}, {Key: "version", Value: int64(2)}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "referenced artist is rejected",
+			artistID: artistID,
+			dbResponse: []bson.D{
+				countResponse(2),
+				countResponse(1),
+			},
+			expectedError: &model.ConflictError{Message: "artist is referenced by 2 artworks and 1 exhibitions"},
+		},
+		{
+			name:     "referenced artist deleted with cascade",
+			artistID: artistID,
+			cascade:  true,
+			dbResponse: []bson.D{
+				writeResponse(bson.D{{Key: "_id", Value: artistObjectID}, {Key: "version", Value: int64(2)}}),
+				mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{artworkOne, artworkTwo}}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: artworkOne}, {Key: "version", Value: int64(2)}},
+					bson.D{{Key: "_id", Value: artworkTwo}, {Key: "version", Value: int64(3)}},
+				),
+				mtest.CreateSuccessResponse(),
+			},
+			expectedArtworks: 2,
+			expectedError:    nil,
+		},
+		{
+			name:     "no artist found with cascade",
+			artistID: artistID,
+			cascade:  true,
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			},
+			expectedError: model.ErrNotFound,
+		},
+		{
+			name:     "no artist found",
+			artistID: artistID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			},
+			expectedError: model.ErrNotFound,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, artworks, err := store.Delete(context.Background(), tc.artistID, tc.cascade)
+			require.Equal(mt, tc.expectedError, err)
+			require.Len(mt, artworks, tc.expectedArtworks)
+		})
+	}
+}
+
+func TestUpsertMany(t *testing.T) {
+	artists := []*model.Artist{
+		{ID: artistObjectID, Name: "name"},
+	}
+
+	testCases := []struct {
+		name             string
+		artists          []*model.Artist
+		dbResponse       []bson.D
+		expectedUpserted int64
+		expectedError    error
+	}{
+		{
+			name:             "nothing to upsert",
+			artists:          []*model.Artist{},
+			dbResponse:       []bson.D{},
+			expectedUpserted: 0,
+			expectedError:    nil,
+		},
+		{
+			name:    "upsert artists",
+			artists: artists,
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(
+					bson.E{Key: "n", Value: 1},
+					bson.E{Key: "nModified", Value: 0},
+					bson.E{Key: "upserted", Value: bson.A{
+						bson.D{{Key: "index", Value: 0}, {Key: "_id", Value: primitive.NewObjectID()}},
+					}},
+				),
+			},
+			expectedUpserted: 1,
+			expectedError:    nil,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			res, err := store.UpsertMany(context.Background(), tc.artists)
+			require.Equal(mt, tc.expectedError, err)
+			require.Equal(mt, tc.expectedUpserted, res.UpsertedCount)
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	testCases := []struct {
+		name            string
+		dbResponse      []bson.D
+		expectedArtists []*model.Artist
+		expectedError   error
+	}{
+		{
+			name: "artists streamed",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch,
+					bson.D{
+						{Key: "_id", Value: artistID},
+						{Key: "name", Value: "artist_name"},
+						{Key: "images", Value: imagesBson},
+					},
+				),
+			},
+			expectedArtists: []*model.Artist{
+				{
+					ID:     artistObjectID,
+					Name:   "artist_name",
+					Images: images,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "stream returns error",
+			dbResponse: []bson.D{
+				MongoFailResponse,
+			},
+			expectedArtists: nil,
+			expectedError:   ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			var artists []*model.Artist
+			store := NewStore(mt.DB)
+			err := store.Stream(context.Background(), func(artist *model.Artist) error {
+				artists = append(artists, artist)
+				return nil
+			})
+			require.Equal(mt, tc.expectedArtists, artists)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	restoredID := primitive.NewObjectID()
+
+	testCases := []struct {
+		name             string
+		artistID         string
+		dbResponse       []bson.D
+		expectedArtworks int
+		expectedError    error
+	}{
+		{
+			name:          "invalid artistID",
+			artistID:      "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:     "artist and its artworks restored",
+			artistID: artistID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{
+					{Key: "_id", Value: artistObjectID},
+					{Key: "deleted_at", Value: primitive.NewDateTimeFromTime(time.Now())},
+				}),
+				writeResponse(bson.D{{Key: "_id", Value: artistObjectID}, {Key: "version", Value: int64(2)}}),
+				mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{restoredID}}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: restoredID}, {Key: "version", Value: int64(3)}},
+				),
+				mtest.CreateSuccessResponse(),
+			},
+			expectedArtworks: 1,
+			expectedError:    nil,
+		},
+		{
+			name:     "artist not in trash",
+			artistID: artistID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch),
+			},
+			expectedError: model.ErrNotFound,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, artworks, err := store.Restore(context.Background(), tc.artistID)
+			require.Equal(mt, tc.expectedError, err)
+			require.Len(mt, artworks, tc.expectedArtworks)
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	testCases := []struct {
+		name          string
+		artistID      string
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid artistID",
+			artistID:      "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:     "artist purged",
+			artistID: artistID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
+				writeResponse(bson.D{{Key: "_id", Value: artistObjectID}, {Key: "version", Value: int64(2)}}),
+				mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{primitive.NewObjectID()}}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+				mtest.CreateSuccessResponse(),
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "artist still has artworks",
+			artistID: artistID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "n", Value: 3}}),
+			},
+			expectedError: &model.ConflictError{Message: "artist is referenced by 3 artworks"},
+		},
+		{
+			name:     "artist not in trash",
+			artistID: artistID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+			},
+			expectedError: model.ErrNotFound,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.Purge(context.Background(), tc.artistID)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
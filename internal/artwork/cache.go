@@ -7,7 +7,7 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v9"
-	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/util"
 )
 
 type Cache struct {
@@ -24,26 +24,53 @@ func NewCache(client *redis.Client, expiration time.Duration) *Cache {
 	}
 }
 
-func (c *Cache) Get(ctx context.Context, artworkID string) (*model.Artwork, error) {
-	key := c.getKeyByID(artworkID)
-	val, err := c.client.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
+func (c *Cache) Get(ctx context.Context, artworkID string) (*util.Response, error) {
+	return c.get(ctx, c.getKeyByID(artworkID))
+}
 
-	var artwork model.Artwork
-	err = json.Unmarshal([]byte(val), &artwork)
-	if err != nil {
-		return nil, err
+func (c *Cache) GetMany(ctx context.Context, queryString string) (*util.Response, error) {
+	return c.get(ctx, c.getKeyByQuery(queryString))
+}
+
+func (c *Cache) Set(ctx context.Context, artworkID string, res *util.Response) error {
+	return c.set(ctx, c.getKeyByID(artworkID), res)
+}
+
+func (c *Cache) SetMany(ctx context.Context, queryString string, res *util.Response) error {
+	return c.set(ctx, c.getKeyByQuery(queryString), res)
+}
+
+// Invalidate removes the cached artwork and every cached artwork listing
+func (c *Cache) Invalidate(ctx context.Context, artworkID string) error {
+	keys := []string{c.getKeyByID(artworkID)}
+	return c.invalidate(ctx, keys, c.getKeyPatternByQuery())
+}
+
+// InvalidateAll removes every cached artwork and artwork listing, for writes
+// that change many artworks at once such as deleting their artist
+func (c *Cache) InvalidateAll(ctx context.Context) error {
+	return c.invalidate(ctx, nil, c.getKeyPatternByID(), c.getKeyPatternByQuery())
+}
+
+// invalidate removes the keys and every key matching one of the patterns
+func (c *Cache) invalidate(ctx context.Context, keys []string, patterns ...string) error {
+	for _, pattern := range patterns {
+		iter := c.client.Scan(ctx, 0, pattern, 0).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
 	}
 
-	return &artwork, nil
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.client.Del(ctx, keys...).Err()
 }
 
-func (c *Cache) GetMany(ctx context.Context, queryString string) ([]*model.Artwork, error) {
-	key := c.getKeyByQuery(queryString)
+func (c *Cache) get(ctx context.Context, key string) (*util.Response, error) {
 	val, err := c.client.Get(ctx, key).Result()
 	if err == redis.Nil {
 		return nil, nil
@@ -51,35 +78,23 @@ func (c *Cache) GetMany(ctx context.Context, queryString string) ([]*model.Artwo
 		return nil, err
 	}
 
-	var artworks []*model.Artwork
-	err = json.Unmarshal([]byte(val), &artworks)
+	var res util.Response
+	err = json.Unmarshal([]byte(val), &res)
 	if err != nil {
 		return nil, err
 	}
 
-	return artworks, nil
+	return &res, nil
 }
 
-func (c *Cache) Set(ctx context.Context, artworkID string, artwork *model.Artwork) error {
-	artworkJson, err := json.Marshal(artwork)
+// set caches the response body along with its ETag
+func (c *Cache) set(ctx context.Context, key string, res *util.Response) error {
+	resJson, err := json.Marshal(res)
 	if err != nil {
 		return err
 	}
 
-	key := c.getKeyByID(artworkID)
-	err = c.client.Set(ctx, key, artworkJson, c.expiration).Err()
-	return err
-}
-
-func (c *Cache) SetMany(ctx context.Context, queryString string, artworks []*model.Artwork) error {
-	artworksJson, err := json.Marshal(artworks)
-	if err != nil {
-		return err
-	}
-
-	key := c.getKeyByQuery(queryString)
-	err = c.client.Set(ctx, key, artworksJson, c.expiration).Err()
-	return err
+	return c.client.Set(ctx, key, resJson, c.expiration).Err()
 }
 
 func (c *Cache) getKeyByID(artworkID string) string {
@@ -89,3 +104,11 @@ func (c *Cache) getKeyByID(artworkID string) string {
 func (c *Cache) getKeyByQuery(queryString string) string {
 	return fmt.Sprintf("%s?%s", c.namespace, queryString)
 }
+
+func (c *Cache) getKeyPatternByQuery() string {
+	return fmt.Sprintf("%s\\?*", c.namespace)
+}
+
+func (c *Cache) getKeyPatternByID() string {
+	return fmt.Sprintf("%s:*", c.namespace)
+}
@@ -1,30 +1,60 @@
 package artwork
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/iamnotrodger/art-house-api/internal/model"
 	"github.com/iamnotrodger/art-house-api/internal/query"
+	"github.com/iamnotrodger/art-house-api/internal/revision"
 	"github.com/iamnotrodger/art-house-api/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ArtistCache is the cache of the artists, which are cached with their artworks
+type ArtistCache interface {
+	InvalidateArtworks(ctx context.Context) error
+}
+
+// ExhibitionCache is the cache of the exhibitions, which are cached with their
+// artists and artworks
+type ExhibitionCache interface {
+	InvalidateContents(ctx context.Context) error
+	InvalidateAll(ctx context.Context) error
+}
+
 type Handler struct {
-	store *Store
-	cache *Cache
+	store           *Store
+	cache           *Cache
+	artistCache     ArtistCache
+	exhibitionCache ExhibitionCache
+	revisions       *revision.Store
 }
 
-func NewHandler(store *Store, cache *Cache) *Handler {
+func NewHandler(store *Store, cache *Cache, artistCache ArtistCache, exhibitionCache ExhibitionCache, revisions *revision.Store) *Handler {
 	return &Handler{
-		store: store,
-		cache: cache,
+		store:           store,
+		cache:           cache,
+		artistCache:     artistCache,
+		exhibitionCache: exhibitionCache,
+		revisions:       revisions,
 	}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/artwork", h.GetMany).Methods("GET")
+	router.HandleFunc("/api/artwork", h.Create).Methods("POST")
 	router.HandleFunc("/api/artwork/{id}", h.Get).Methods("GET")
+	router.HandleFunc("/api/artwork/{id}", h.Update).Methods("PUT")
+	router.HandleFunc("/api/artwork/{id}", h.Patch).Methods("PATCH")
+	router.HandleFunc("/api/artwork/{id}", h.Delete).Methods("DELETE")
+	router.HandleFunc("/api/admin/trash/artwork", h.GetDeleted).Methods("GET")
+	router.HandleFunc("/api/admin/trash/artwork/{id}/restore", h.Restore).Methods("POST")
+	router.HandleFunc("/api/admin/trash/artwork/{id}", h.Purge).Methods("DELETE")
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
@@ -33,49 +63,323 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	artworkID := params["id"]
 
-	artwork, err := h.cache.Get(r.Context(), artworkID)
+	res, err := h.cache.Get(r.Context(), artworkID)
 	if err != nil {
 		log.Println(err)
-	} else if artwork != nil {
-		json.NewEncoder(w).Encode(artwork)
+	} else if res != nil {
+		res.Write(w, r, http.StatusOK)
 		return
 	}
 
-	artwork, err = h.store.Find(r.Context(), artworkID)
+	artwork, err := h.store.Find(r.Context(), artworkID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	res, err = util.NewVersionedResponse(artwork, artwork.Version)
 	if err != nil {
 		util.HandleError(w, err)
 		return
 	}
-	err = h.cache.Set(r.Context(), artworkID, artwork)
+	err = h.cache.Set(r.Context(), artworkID, res)
 	if err != nil {
 		log.Println(err)
 	}
 
-	json.NewEncoder(w).Encode(artwork)
+	res.Write(w, r, http.StatusOK)
 }
 
 func (h *Handler) GetMany(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	queryParams, err := query.NewArtworkQuery(r.URL.Query())
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, queryParams); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
 	queryString := r.URL.RawQuery
-	artworks, err := h.cache.GetMany(r.Context(), queryString)
+	res, err := h.cache.GetMany(r.Context(), queryString)
+	if err != nil {
+		log.Println(err)
+	} else if res != nil {
+		res.Write(w, r, http.StatusOK)
+		return
+	}
+
+	artworks, page, err := h.store.FindMany(r.Context(), queryParams)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if queryParams.HasFacets() {
+		var facets model.Facets
+		facets, err = h.store.FindFacets(r.Context(), queryParams)
+		if err != nil {
+			util.HandleError(w, err)
+			return
+		}
+		res, err = util.NewFacetedPageResponse(r, artworks, page, facets)
+	} else {
+		res, err = util.NewPageResponse(r, artworks, page)
+	}
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	err = h.cache.SetMany(r.Context(), queryString, res)
+	if err != nil {
+		log.Println(err)
+	}
+
+	res.Write(w, r, http.StatusOK)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var artwork model.Artwork
+	err := json.NewDecoder(r.Body).Decode(&artwork)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	err = artwork.Validate()
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	// the store assigns the ID of a new artwork, one sent along is ignored
+	artwork.ID = primitive.NilObjectID
+
+	doc, err := h.store.InsertOne(r.Context(), &artwork)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionCreate)
+
+	h.respondWithArtwork(w, r, artwork.ID.Hex(), http.StatusCreated, false)
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	artworkID := params["id"]
+
+	version, err := util.GetIfMatch(r, func() (int64, error) {
+		return h.store.Version(r.Context(), artworkID)
+	})
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	var artwork model.Artwork
+	err = json.NewDecoder(r.Body).Decode(&artwork)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	err = artwork.Validate()
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	doc, err := h.store.Update(r.Context(), artworkID, &artwork, version)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionUpdate)
+
+	h.respondWithArtwork(w, r, artworkID, http.StatusOK, true)
+}
+
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	artworkID := params["id"]
+
+	version, err := util.GetIfMatch(r, func() (int64, error) {
+		return h.store.Version(r.Context(), artworkID)
+	})
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	var patch model.ArtworkPatch
+	err = json.NewDecoder(r.Body).Decode(&patch)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	err = patch.Validate()
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	doc, err := h.store.Patch(r.Context(), artworkID, &patch, version)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionUpdate)
+
+	h.respondWithArtwork(w, r, artworkID, http.StatusOK, patch.Artist != nil)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	artworkID := params["id"]
+
+	doc, err := h.store.Delete(r.Context(), artworkID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionDelete)
+
+	err = h.cache.Invalidate(r.Context(), artworkID)
+	if err != nil {
+		log.Println(err)
+	}
+	h.invalidateRelated(r.Context(), false)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetDeleted(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	queryParams, err := query.NewArtworkQuery(r.URL.Query(), query.DeletedSort)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, queryParams); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	artworks, page, err := h.store.FindDeleted(r.Context(), queryParams)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	util.RespondWithPage(w, r, http.StatusOK, artworks, page)
+}
+
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	artworkID := params["id"]
+
+	doc, err := h.store.Restore(r.Context(), artworkID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionRestore)
+
+	h.respondWithArtwork(w, r, artworkID, http.StatusOK, false)
+}
+
+func (h *Handler) Purge(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	artworkID := params["id"]
+
+	doc, err := h.store.Purge(r.Context(), artworkID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionPurge)
+
+	err = h.cache.Invalidate(r.Context(), artworkID)
 	if err != nil {
 		log.Println(err)
-	} else if artworks != nil {
-		json.NewEncoder(w).Encode(artworks)
+	}
+	h.invalidateRelated(r.Context(), true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithArtwork invalidates the cached artwork and the caches that hold it after a
+// write, and responds with its stored state. inExhibitions is set when the write
+// changed the exhibitions that hold the artwork.
+func (h *Handler) respondWithArtwork(w http.ResponseWriter, r *http.Request, artworkID string, statusCode int, inExhibitions bool) {
+	err := h.cache.Invalidate(r.Context(), artworkID)
+	if err != nil {
+		log.Println(err)
+	}
+	h.invalidateRelated(r.Context(), inExhibitions)
+
+	artwork, err := h.store.Find(r.Context(), artworkID)
+	if err != nil {
+		util.HandleError(w, err)
 		return
 	}
 
-	queryParams := query.NewArtworkQuery(r.URL.Query())
-	artworks, err = h.store.FindMany(r.Context(), queryParams)
+	res, err := util.NewVersionedResponse(artwork, artwork.Version)
 	if err != nil {
 		util.HandleError(w, err)
 		return
 	}
-	err = h.cache.SetMany(r.Context(), queryString, artworks)
+
+	res.Write(w, r, statusCode)
+}
+
+// SyncReverted brings the exhibitions that hold the artwork back in line with
+// it after it is reverted, and removes the cached entries that hold it
+func (h *Handler) SyncReverted(ctx context.Context, artworkID string) error {
+	err := h.store.SyncExhibitions(ctx, artworkID)
+	if err != nil {
+		return err
+	}
+
+	err = h.cache.Invalidate(ctx, artworkID)
 	if err != nil {
 		log.Println(err)
 	}
+	h.invalidateRelated(ctx, true)
+
+	return nil
+}
 
-	json.NewEncoder(w).Encode(artworks)
+// invalidateRelated removes the cached artists and exhibitions that a write to
+// an artwork makes stale, since both are cached with their artworks.
+// inExhibitions is set when the write changed the exhibitions themselves.
+func (h *Handler) invalidateRelated(ctx context.Context, inExhibitions bool) {
+	err := h.artistCache.InvalidateArtworks(ctx)
+	if err != nil {
+		log.Println(err)
+	}
+
+	if inExhibitions {
+		err = h.exhibitionCache.InvalidateAll(ctx)
+	} else {
+		err = h.exhibitionCache.InvalidateContents(ctx)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// recordRevision snapshots the artwork as a write left it, a failure does not fail the write
+func (h *Handler) recordRevision(r *http.Request, document bson.M, action string) {
+	_, err := h.revisions.Record(r.Context(), "artworks", action, revision.GetAuthor(r), document)
+	if err != nil {
+		log.Println(err)
+	}
 }
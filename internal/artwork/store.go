@@ -3,20 +3,25 @@ package artwork
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/iamnotrodger/art-house-api/internal/model"
 	"github.com/iamnotrodger/art-house-api/internal/query"
+	"github.com/iamnotrodger/art-house-api/internal/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Store struct {
+	db         *mongo.Database
 	collection *mongo.Collection
 }
 
 func NewStore(db *mongo.Database) *Store {
 	return &Store{
+		db:         db,
 		collection: db.Collection("artworks"),
 	}
 }
@@ -26,10 +31,10 @@ func (s *Store) Find(ctx context.Context, artworkID string) (*model.Artwork, err
 
 	id, err := primitive.ObjectIDFromHex(artworkID)
 	if err != nil {
-		return nil, primitive.ErrInvalidHex
+		return nil, model.ErrInvalidID
 	}
 
-	match := bson.D{{Key: "$match", Value: bson.M{"_id": id}}}
+	match := bson.D{{Key: "$match", Value: bson.M{"_id": id, "deleted_at": nil}}}
 	limit := bson.D{{Key: "$limit", Value: 1}}
 
 	pipeline := mongo.Pipeline{match, limit, query.ArtworkLookupStage, query.ArtworkUnwindStage}
@@ -40,7 +45,7 @@ func (s *Store) Find(ctx context.Context, artworkID string) (*model.Artwork, err
 	defer cursor.Close(ctx)
 
 	if cursor.RemainingBatchLength() < 1 {
-		return nil, mongo.ErrNoDocuments
+		return nil, model.ErrNotFound
 	}
 
 	cursor.Next(ctx)
@@ -56,33 +61,73 @@ func (s *Store) Find(ctx context.Context, artworkID string) (*model.Artwork, err
 	return &artwork, nil
 }
 
-func (s *Store) FindMany(ctx context.Context, queryParam ...query.QueryParams) ([]*model.Artwork, error) {
-	pipeline := mongo.Pipeline{}
+func (s *Store) FindMany(ctx context.Context, queryParam ...query.QueryParams) ([]*model.Artwork, *model.Page, error) {
+	err := query.FindExhibitionArtworks(ctx, s.db, queryParam...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pipeline := mongo.Pipeline{query.NotDeletedStage}
 
 	if len(queryParam) > 0 {
 		pipeline = queryParam[0].GetPipeline()
 	}
-	pipeline = append(pipeline, query.ArtworkLookupStage, query.ArtworkUnwindStage)
+	pipeline = query.JoinArtist(pipeline)
 
 	cursor, err := s.collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer cursor.Close(ctx)
 
-	artworks := []*model.Artwork{}
-	err = cursor.All(ctx, &artworks)
+	artworks, last, err := decodeArtworks(ctx, cursor)
 	if err != nil {
-		err = fmt.Errorf("failed to unmarshal artworks: %w", err)
-		return nil, err
+		return nil, nil, err
+	}
+	var total int64
+	if len(queryParam) > 0 {
+		total, err = query.Count(ctx, s.collection, queryParam[0].GetCountPipeline())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	page, err := query.NewPage(len(artworks), last, total, queryParam...)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	for _, artwork := range artworks {
 		model.SortImages(artwork.Images)
-		model.SortImages(artwork.Artist.Images)
+		// the artist is left out when the query selects fields without it
+		if artwork.Artist != nil {
+			model.SortImages(artwork.Artist.Images)
+		}
+	}
+
+	return artworks, page, nil
+}
+
+// FindFacets counts the whole list of artworks of the query into the buckets of its facets
+func (s *Store) FindFacets(ctx context.Context, queryParam *query.ArtworkQueryParams) (model.Facets, error) {
+	err := query.FindExhibitionArtworks(ctx, s.db, queryParam)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, queryParam.GetFacetPipeline())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	facets := model.Facets{}
+	if cursor.Next(ctx) {
+		if err = cursor.Decode(&facets); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal facets: %w", err)
+		}
 	}
 
-	return artworks, nil
+	return facets, cursor.Err()
 }
 
 func (s *Store) InsertMany(ctx context.Context, artworks []*model.Artwork) error {
@@ -96,3 +141,374 @@ func (s *Store) InsertMany(ctx context.Context, artworks []*model.Artwork) error
 	_, err := s.collection.InsertMany(ctx, docs)
 	return err
 }
+
+// Stream decodes every artwork with its artist one at a time from the cursor and passes it to fn
+func (s *Store) Stream(ctx context.Context, fn func(*model.Artwork) error) error {
+	pipeline := mongo.Pipeline{query.NotDeletedStage, query.ArtworkLookupStage, query.ArtworkUnwindStage}
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var artwork model.Artwork
+		err = cursor.Decode(&artwork)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal artwork: %w", err)
+		}
+
+		model.SortImages(artwork.Images)
+		model.SortImages(artwork.Artist.Images)
+		if err = fn(&artwork); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// UpsertMany replaces the artworks matching by ID, or by title and artist when
+// an artwork has no ID, and inserts the ones that do not exist yet
+func (s *Store) UpsertMany(ctx context.Context, artworks []*model.Artwork) (*mongo.BulkWriteResult, error) {
+	writes := []mongo.WriteModel{}
+
+	for _, artwork := range artworks {
+		filter := bson.M{"_id": artwork.ID}
+		if artwork.ID.IsZero() {
+			filter = bson.M{"title": artwork.Title, "artist_id": artwork.Artist.ID}
+		}
+
+		model.SortImages(artwork.Images)
+		write := mongo.NewReplaceOneModel().
+			SetFilter(filter).
+			SetReplacement(artwork.ConvertToBson()).
+			SetUpsert(true)
+		writes = append(writes, write)
+	}
+
+	if len(writes) == 0 {
+		return &mongo.BulkWriteResult{}, nil
+	}
+	return s.collection.BulkWrite(ctx, writes)
+}
+
+// InsertOne stores the artwork and returns it as it was stored
+func (s *Store) InsertOne(ctx context.Context, artwork *model.Artwork) (bson.M, error) {
+	err := s.validateArtist(ctx, artwork.Artist.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if artwork.ID.IsZero() {
+		artwork.ID = primitive.NewObjectID()
+	}
+	artwork.Version = 1
+	model.SortImages(artwork.Images)
+
+	doc := artwork.ConvertToBson()
+	_, err = s.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	return util.StoredDocument(doc)
+}
+
+// Update replaces the artwork if it is still stored at version, and returns it
+// as it was stored
+func (s *Store) Update(ctx context.Context, artworkID string, artwork *model.Artwork, version int64) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(artworkID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	err = s.validateArtist(ctx, artwork.Artist.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	artwork.ID = id
+	artwork.Version = version + 1
+	model.SortImages(artwork.Images)
+
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "deleted_at", Value: nil}, query.VersionFilter(version)}
+	doc, err := util.ReplaceAndReturn(ctx, s.collection, filter, artwork.ConvertToBson())
+	if err == mongo.ErrNoDocuments {
+		return nil, s.modifiedError(ctx, id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = s.syncExhibitions(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Patch updates the fields of the artwork if it is still stored at version, and
+// returns it as it was stored
+func (s *Store) Patch(ctx context.Context, artworkID string, patch *model.ArtworkPatch, version int64) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(artworkID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	if patch.Artist != nil {
+		err = s.validateArtist(ctx, patch.Artist.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	model.SortImages(patch.Images)
+
+	update := bson.D{
+		{Key: "$set", Value: patch.ConvertToBson()},
+		{Key: "$inc", Value: bson.M{"version": 1}},
+	}
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "deleted_at", Value: nil}, query.VersionFilter(version)}
+	doc, err := util.UpdateAndReturn(ctx, s.collection, filter, update)
+	if err == mongo.ErrNoDocuments {
+		return nil, s.modifiedError(ctx, id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if patch.Artist != nil {
+		err = s.syncExhibitions(ctx, id, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// Version returns the version the artwork is stored at
+func (s *Store) Version(ctx context.Context, artworkID string) (int64, error) {
+	return util.FindVersion(ctx, s.collection, artworkID)
+}
+
+// Delete moves the artwork to the trash and returns it as it was stored
+func (s *Store) Delete(ctx context.Context, artworkID string) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(artworkID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	update := bson.M{"$set": bson.M{"deleted_at": time.Now()}, "$inc": bson.M{"version": 1}}
+	doc, err := util.UpdateAndReturn(ctx, s.collection, bson.M{"_id": id, "deleted_at": nil}, update)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrNotFound
+	}
+	return doc, err
+}
+
+// FindDeleted returns the artworks in the trash, most recently deleted first
+func (s *Store) FindDeleted(ctx context.Context, queryParam ...query.QueryParams) ([]*model.Artwork, *model.Page, error) {
+	opts := options.Find().SetSort(bson.D{query.DeletedSort})
+	filter := append(bson.D{}, query.DeletedFilter...)
+	if len(queryParam) > 0 {
+		opts = queryParam[0].GetFindOptions()
+		filter = append(filter, queryParam[0].GetCursorFilter()...)
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	artworks, last, err := decodeArtworks(ctx, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	var total int64
+	if len(queryParam) > 0 {
+		total, err = query.Count(ctx, s.collection, query.CountPipeline(query.DeletedFilter))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	page, err := query.NewPage(len(artworks), last, total, queryParam...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, artwork := range artworks {
+		model.SortImages(artwork.Images)
+	}
+
+	return artworks, page, nil
+}
+
+// Restore moves the artwork out of the trash and returns it as it was stored
+func (s *Store) Restore(ctx context.Context, artworkID string) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(artworkID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	update := bson.M{"$unset": bson.M{"deleted_at": ""}, "$inc": bson.M{"version": 1}}
+	doc, err := util.UpdateAndReturn(ctx, s.collection, bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}}, update)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrNotFound
+	}
+	return doc, err
+}
+
+// Purge permanently removes the artwork from the trash and from the exhibitions,
+// and returns it as it was stored before it was removed
+func (s *Store) Purge(ctx context.Context, artworkID string) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(artworkID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	doc, err := util.DeleteAndReturn(ctx, s.collection, bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}})
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = s.syncExhibitions(ctx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ValidateSnapshot checks a snapshot of an artwork as Update checks an artwork,
+// before the artwork is reverted to it
+func (s *Store) ValidateSnapshot(ctx context.Context, snapshot bson.M) error {
+	raw, err := bson.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	var stored struct {
+		model.Artwork `bson:",inline"`
+		ArtistID      primitive.ObjectID `bson:"artist_id"`
+	}
+	err = bson.Unmarshal(raw, &stored)
+	if err != nil {
+		return fmt.Errorf("error decoding artwork: %w", err)
+	}
+
+	artwork := stored.Artwork
+	artwork.Artist = &model.Artist{ID: stored.ArtistID}
+	err = artwork.Validate()
+	if err != nil {
+		return err
+	}
+	return s.validateArtist(ctx, artwork.Artist.ID)
+}
+
+// SyncExhibitions sets the artists of the exhibitions that hold the artwork to
+// the artists of their artworks, after the artwork was reverted to a snapshot
+// that may have another artist
+func (s *Store) SyncExhibitions(ctx context.Context, artworkID string) error {
+	id, err := primitive.ObjectIDFromHex(artworkID)
+	if err != nil {
+		return model.ErrInvalidID
+	}
+	return s.syncExhibitions(ctx, id, false)
+}
+
+// syncExhibitions sets the artists of the exhibitions that hold the artwork to
+// the artists of their artworks, after the artwork's artist changed or it was
+// purged. A purged artwork is also pulled from the exhibitions.
+func (s *Store) syncExhibitions(ctx context.Context, id primitive.ObjectID, purged bool) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"artwork_ids": id}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "artworks",
+			"localField":   "artwork_ids",
+			"foreignField": "_id",
+			"as":           "artworks",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"artist_ids": bson.M{"$setUnion": bson.A{"$artworks.artist_id", bson.A{}}},
+			"current":    bson.M{"$ifNull": bson.A{"$artist_ids", bson.A{}}},
+		}}},
+	}
+	if !purged {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{
+			"$expr": bson.M{"$not": bson.A{bson.M{"$setEquals": bson.A{"$artist_ids", "$current"}}}},
+		}}})
+	}
+
+	collection := s.db.Collection("exhibitions")
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	var exhibitions []struct {
+		ID        primitive.ObjectID   `bson:"_id"`
+		ArtistIDs []primitive.ObjectID `bson:"artist_ids"`
+	}
+	err = cursor.All(ctx, &exhibitions)
+	if err != nil {
+		return fmt.Errorf("error decoding exhibition: %w", err)
+	}
+
+	writes := []mongo.WriteModel{}
+	for _, exhibition := range exhibitions {
+		update := bson.M{"$set": bson.M{"artist_ids": exhibition.ArtistIDs}, "$inc": bson.M{"version": 1}}
+		if purged {
+			update["$pull"] = bson.M{"artwork_ids": id}
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": exhibition.ID, "artwork_ids": id}).
+			SetUpdate(update))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	_, err = collection.BulkWrite(ctx, writes)
+	return err
+}
+
+// validateArtist checks that the artwork's artist exists
+func (s *Store) validateArtist(ctx context.Context, artistID primitive.ObjectID) error {
+	count, err := s.db.Collection("artists").CountDocuments(ctx, bson.M{"_id": artistID, "deleted_at": nil})
+	if err != nil {
+		return err
+	}
+	if count < 1 {
+		return &model.ValidationError{Field: "artist", Message: "does not exist"}
+	}
+	return nil
+}
+
+// modifiedError reports why a versioned write matched no artwork
+func (s *Store) modifiedError(ctx context.Context, id primitive.ObjectID) error {
+	count, err := s.collection.CountDocuments(ctx, bson.M{"_id": id, "deleted_at": nil})
+	if err != nil {
+		return err
+	}
+	if count < 1 {
+		return model.ErrNotFound
+	}
+	return &model.PreconditionError{Message: "artwork has been modified"}
+}
+
+// decodeArtworks decodes the artworks of the cursor and returns the last
+// document, which positions the cursor of the next page
+func decodeArtworks(ctx context.Context, cursor *mongo.Cursor) ([]*model.Artwork, bson.Raw, error) {
+	var last bson.Raw
+	artworks := []*model.Artwork{}
+	for cursor.Next(ctx) {
+		var artwork model.Artwork
+		if err := cursor.Decode(&artwork); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal artworks: %w", err)
+		}
+		artworks = append(artworks, &artwork)
+		last = cursor.Current
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return artworks, last, nil
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/query"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,7 +16,8 @@ import (
 
 var (
 	MongoFailResponse    = bson.D{{Key: "ok", Value: 0}}
-	ErrMongoCommandError = mongo.CommandError{Message: "command failed"}
+	MongoFailRaw, _      = bson.Marshal(MongoFailResponse)
+	ErrMongoCommandError = mongo.CommandError{Message: "command failed", Raw: MongoFailRaw}
 	ErrMongoNoResponses  = mongo.CommandError{Message: "no responses remaining", Labels: []string{"NetworkError"}, Wrapped: errors.New("no responses remaining")}
 
 	artworkID          = "60e0850266d6c13d7b599b69"
@@ -57,6 +59,11 @@ var (
 	}
 )
 
+// writeResponse answers a write that returns the document as it left it stored
+func writeResponse(doc bson.D) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: doc})
+}
+
 func TestFind(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -70,7 +77,7 @@ func TestFind(t *testing.T) {
 			artworkID:       "invalid_ID",
 			dbResponse:      []bson.D{},
 			expectedArtwork: nil,
-			expectedError:   primitive.ErrInvalidHex,
+			expectedError:   model.ErrInvalidID,
 		},
 		{
 			name:      "no artwork found",
@@ -79,7 +86,7 @@ func TestFind(t *testing.T) {
 				mtest.CreateCursorResponse(0, "art-house.artwork", mtest.FirstBatch),
 			},
 			expectedArtwork: nil,
-			expectedError:   mongo.ErrNoDocuments,
+			expectedError:   model.ErrNotFound,
 		},
 		{
 			name:      "artwork found",
@@ -196,13 +203,153 @@ func TestFindMany(t *testing.T) {
 			mt.AddMockResponses(tc.dbResponse...)
 
 			store := NewStore(mt.DB)
-			artwork, err := store.FindMany(context.Background())
+			artwork, _, err := store.FindMany(context.Background())
 			require.Equal(mt, tc.expectedArtwork, artwork)
 			require.Equal(mt, tc.expectedError, err)
 		})
 	}
 }
 
+func TestFindManyPage(t *testing.T) {
+	artworkDoc := bson.D{
+		{Key: "_id", Value: artworkObjectID},
+		{Key: "title", Value: "title"},
+		{Key: "artist", Value: artist},
+	}
+
+	testCases := []struct {
+		name           string
+		dbResponse     []bson.D
+		expectedTotal  int64
+		expectedCursor bool
+		expectedError  error
+	}{
+		{
+			name: "more artworks after the page",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, artworkDoc),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "total", Value: 3}}),
+			},
+			expectedTotal:  3,
+			expectedCursor: true,
+			expectedError:  nil,
+		},
+		{
+			name: "last page",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, artworkDoc),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "total", Value: 1}}),
+			},
+			expectedTotal:  1,
+			expectedCursor: false,
+			expectedError:  nil,
+		},
+		{
+			name: "count returns error",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, artworkDoc),
+				MongoFailResponse,
+			},
+			expectedError: ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			queryParams, err := query.NewArtworkQuery(map[string][]string{"limit": {"1"}})
+			require.NoError(mt, err)
+
+			store := NewStore(mt.DB)
+			_, page, err := store.FindMany(context.Background(), queryParams)
+			require.Equal(mt, tc.expectedError, err)
+			if err != nil {
+				return
+			}
+			require.Equal(mt, tc.expectedTotal, page.Total)
+			require.Equal(mt, int64(1), page.Limit)
+			require.Equal(mt, tc.expectedCursor, page.NextCursor != "")
+		})
+	}
+}
+
+func TestFindManyFields(t *testing.T) {
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	m.Run("artist left out", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: artworkObjectID},
+				{Key: "title", Value: "title"},
+			}),
+			mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "total", Value: 1}}),
+		)
+
+		queryParams, err := query.NewArtworkQuery(map[string][]string{"fields": {"title"}})
+		require.NoError(mt, err)
+
+		store := NewStore(mt.DB)
+		artworks, _, err := store.FindMany(context.Background(), queryParams)
+		require.NoError(mt, err)
+		require.Equal(mt, []*model.Artwork{{ID: artworkObjectID, Title: "title"}}, artworks)
+	})
+}
+
+func TestFindFacets(t *testing.T) {
+	exhibitionObjectID := primitive.NewObjectID()
+
+	testCases := []struct {
+		name           string
+		dbResponse     []bson.D
+		expectedFacets model.Facets
+		expectedError  error
+	}{
+		{
+			name: "facets counted",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{
+					{Key: "decade", Value: bson.A{
+						bson.D{{Key: "_id", Value: int32(1880)}, {Key: "count", Value: int64(2)}},
+					}},
+					{Key: "exhibition", Value: bson.A{
+						bson.D{{Key: "_id", Value: exhibitionObjectID}, {Key: "name", Value: "name"}, {Key: "count", Value: int64(1)}},
+					}},
+				}),
+			},
+			expectedFacets: model.Facets{
+				"decade":     {{Value: int32(1880), Count: 2}},
+				"exhibition": {{Value: exhibitionObjectID, Name: "name", Count: 1}},
+			},
+			expectedError: nil,
+		},
+		{
+			name:           "aggregate returns error",
+			dbResponse:     []bson.D{MongoFailResponse},
+			expectedFacets: nil,
+			expectedError:  ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			queryParams, err := query.NewArtworkQuery(map[string][]string{"facets": {"decade,exhibition"}})
+			require.NoError(mt, err)
+
+			store := NewStore(mt.DB)
+			facets, err := store.FindFacets(context.Background(), queryParams)
+			require.Equal(mt, tc.expectedError, err)
+			require.Equal(mt, tc.expectedFacets, facets)
+		})
+	}
+}
+
 func TestInsertMany(t *testing.T) {
 	artworkObjectIDTwo, _ := primitive.ObjectIDFromHex("60e0850266d6c13d7b599b6b")
 	artworks := []*model.Artwork{
@@ -256,3 +403,582 @@ func TestInsertMany(t *testing.T) {
 		})
 	}
 }
+
+func TestInsertOne(t *testing.T) {
+	testCases := []struct {
+		name          string
+		artwork       *model.Artwork
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:    "insert artwork",
+			artwork: &model.Artwork{Title: "title", Artist: artist},
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				mtest.CreateSuccessResponse(),
+			},
+			expectedError: nil,
+		},
+		{
+			name:    "artist does not exist",
+			artwork: &model.Artwork{Title: "title", Artist: artist},
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch),
+			},
+			expectedError: &model.ValidationError{Field: "artist", Message: "does not exist"},
+		},
+		{
+			name:    "insert fails with an error",
+			artwork: &model.Artwork{Title: "title", Artist: artist},
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				MongoFailResponse,
+			},
+			expectedError: ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.InsertOne(context.Background(), tc.artwork)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		artworkID     string
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid artworkID",
+			artworkID:     "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:      "artwork updated",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				writeResponse(bson.D{{Key: "_id", Value: artworkObjectID}, {Key: "version", Value: int64(2)}}),
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch),
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "artwork updated with its exhibitions synced",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				writeResponse(bson.D{{Key: "_id", Value: artworkObjectID}, {Key: "version", Value: int64(2)}}),
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, bson.D{
+					{Key: "_id", Value: primitive.NewObjectID()},
+					{Key: "artist_ids", Value: bson.A{artist.ID}},
+				}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "exhibitions sync fails with an error",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				writeResponse(bson.D{{Key: "_id", Value: artworkObjectID}, {Key: "version", Value: int64(2)}}),
+				MongoFailResponse,
+			},
+			expectedError: ErrMongoCommandError,
+		},
+		{
+			name:      "no artwork found",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
+			},
+			expectedError: model.ErrNotFound,
+		},
+		{
+			name:      "artwork modified since version",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			},
+			expectedError: &model.PreconditionError{Message: "artwork has been modified"},
+		},
+		{
+			name:      "update fails with an error",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				MongoFailResponse,
+			},
+			expectedError: ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.Update(context.Background(), tc.artworkID, &model.Artwork{Title: "title", Artist: artist}, 1)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+	title := "title"
+
+	testCases := []struct {
+		name          string
+		artworkID     string
+		patch         *model.ArtworkPatch
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid artworkID",
+			artworkID:     "invalid_ID",
+			patch:         &model.ArtworkPatch{Title: &title},
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:      "artwork patched",
+			artworkID: artworkID,
+			patch:     &model.ArtworkPatch{Title: &title},
+			dbResponse: []bson.D{
+				writeResponse(bson.D{{Key: "_id", Value: artworkObjectID}, {Key: "version", Value: int64(2)}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "artist patched with its exhibitions synced",
+			artworkID: artworkID,
+			patch:     &model.ArtworkPatch{Artist: artist},
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				writeResponse(bson.D{{Key: "_id", Value: artworkObjectID}, {Key: "version", Value: int64(2)}}),
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, bson.D{
+					{Key: "_id", Value: primitive.NewObjectID()},
+					{Key: "artist_ids", Value: bson.A{artist.ID}},
+				}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "patched artist does not exist",
+			artworkID: artworkID,
+			patch:     &model.ArtworkPatch{Artist: artist},
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch),
+			},
+			expectedError: &model.ValidationError{Field: "artist", Message: "does not exist"},
+		},
+		{
+			name:      "no artwork found",
+			artworkID: artworkID,
+			patch:     &model.ArtworkPatch{Title: &title},
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
+			},
+			expectedError: model.ErrNotFound,
+		},
+		{
+			name:      "artwork modified since version",
+			artworkID: artworkID,
+			patch:     &model.ArtworkPatch{Title: &title},
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			},
+			expectedError: &model.PreconditionError{Message: "artwork has been modified"},
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.Patch(context.Background(), tc.artworkID, tc.patch, 1)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	testCases := []struct {
+		name          string
+		artworkID     string
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid artworkID",
+			artworkID:     "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:      "artwork deleted",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				writeResponse(bson.D{{Key: "_id", Value: artworkObjectID}, {Key: "version", Value: int64(2)}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "no artwork found",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			},
+			expectedError: model.ErrNotFound,
+		},
+		{
+			name:      "delete fails with an error",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				MongoFailResponse,
+			},
+			expectedError: ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.Delete(context.Background(), tc.artworkID)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestUpsertMany(t *testing.T) {
+	artworks := []*model.Artwork{
+		{ID: artworkObjectID, Title: "title", Artist: artist},
+	}
+
+	testCases := []struct {
+		name             string
+		artworks         []*model.Artwork
+		dbResponse       []bson.D
+		expectedUpserted int64
+		expectedError    error
+	}{
+		{
+			name:             "nothing to upsert",
+			artworks:         []*model.Artwork{},
+			dbResponse:       []bson.D{},
+			expectedUpserted: 0,
+			expectedError:    nil,
+		},
+		{
+			name:     "upsert artworks",
+			artworks: artworks,
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(
+					bson.E{Key: "n", Value: 1},
+					bson.E{Key: "nModified", Value: 0},
+					bson.E{Key: "upserted", Value: bson.A{
+						bson.D{{Key: "index", Value: 0}, {Key: "_id", Value: primitive.NewObjectID()}},
+					}},
+				),
+			},
+			expectedUpserted: 1,
+			expectedError:    nil,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			res, err := store.UpsertMany(context.Background(), tc.artworks)
+			require.Equal(mt, tc.expectedError, err)
+			require.Equal(mt, tc.expectedUpserted, res.UpsertedCount)
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	testCases := []struct {
+		name             string
+		dbResponse       []bson.D
+		expectedArtworks []*model.Artwork
+		expectedError    error
+	}{
+		{
+			name: "artworks streamed",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch,
+					bson.D{
+						{Key: "_id", Value: artworkID},
+						{Key: "title", Value: "title_one"},
+						{Key: "images", Value: imagesBson},
+						{Key: "artist", Value: artist},
+					},
+				),
+			},
+			expectedArtworks: []*model.Artwork{
+				{
+					ID:     artworkObjectID,
+					Title:  "title_one",
+					Images: images,
+					Artist: artist,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "stream returns error",
+			dbResponse: []bson.D{
+				MongoFailResponse,
+			},
+			expectedArtworks: nil,
+			expectedError:    ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			var artworks []*model.Artwork
+			store := NewStore(mt.DB)
+			err := store.Stream(context.Background(), func(artwork *model.Artwork) error {
+				artworks = append(artworks, artwork)
+				return nil
+			})
+			require.Equal(mt, tc.expectedArtworks, artworks)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestFindDeleted(t *testing.T) {
+	testCases := []struct {
+		name             string
+		dbResponse       []bson.D
+		expectedArtworks []*model.Artwork
+		expectedError    error
+	}{
+		{
+			name: "trash is empty",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
+			},
+			expectedArtworks: []*model.Artwork{},
+			expectedError:    nil,
+		},
+		{
+			name: "deleted artworks found",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch,
+					bson.D{
+						{Key: "_id", Value: artworkID},
+						{Key: "title", Value: "title_one"},
+						{Key: "images", Value: imagesBson},
+					},
+				),
+			},
+			expectedArtworks: []*model.Artwork{
+				{
+					ID:     artworkObjectID,
+					Title:  "title_one",
+					Images: images,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "find returns error",
+			dbResponse: []bson.D{
+				MongoFailResponse,
+			},
+			expectedArtworks: nil,
+			expectedError:    ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			artworks, _, err := store.FindDeleted(context.Background())
+			require.Equal(mt, tc.expectedArtworks, artworks)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	testCases := []struct {
+		name          string
+		artworkID     string
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid artworkID",
+			artworkID:     "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:      "artwork restored",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				writeResponse(bson.D{{Key: "_id", Value: artworkObjectID}, {Key: "version", Value: int64(2)}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "artwork not in trash",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			},
+			expectedError: model.ErrNotFound,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.Restore(context.Background(), tc.artworkID)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	testCases := []struct {
+		name          string
+		artworkID     string
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid artworkID",
+			artworkID:     "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:      "artwork purged",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				writeResponse(bson.D{{Key: "_id", Value: artworkObjectID}, {Key: "version", Value: int64(2)}}),
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, bson.D{
+					{Key: "_id", Value: primitive.NewObjectID()},
+					{Key: "artist_ids", Value: bson.A{}},
+				}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "artwork not in trash",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+			},
+			expectedError: model.ErrNotFound,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.Purge(context.Background(), tc.artworkID)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestValidateSnapshot(t *testing.T) {
+	testCases := []struct {
+		name          string
+		snapshot      bson.M
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:     "valid snapshot",
+			snapshot: bson.M{"_id": artworkObjectID, "title": "title", "year": int32(1889), "artist_id": artistObjectID},
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "snapshot without title",
+			snapshot:      bson.M{"_id": artworkObjectID, "year": int32(1889), "artist_id": artistObjectID},
+			dbResponse:    []bson.D{},
+			expectedError: &model.ValidationError{Field: "title", Message: "is required"},
+		},
+		{
+			name:     "snapshot of a deleted artist",
+			snapshot: bson.M{"_id": artworkObjectID, "title": "title", "year": int32(1889), "artist_id": artistObjectID},
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch),
+			},
+			expectedError: &model.ValidationError{Field: "artist", Message: "does not exist"},
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			err := store.ValidateSnapshot(context.Background(), tc.snapshot)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
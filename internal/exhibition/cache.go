@@ -7,7 +7,7 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v9"
-	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/util"
 )
 
 type Cache struct {
@@ -24,120 +24,117 @@ func NewCache(client *redis.Client, expiration time.Duration) *Cache {
 	}
 }
 
-func (c *Cache) Get(ctx context.Context, exhibitionID string) (*model.Exhibition, error) {
-	key := c.getKeyByID(exhibitionID)
-	val, err := c.client.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
-
-	var exhibition model.Exhibition
-	err = json.Unmarshal([]byte(val), &exhibition)
-	if err != nil {
-		return nil, err
-	}
+func (c *Cache) Get(ctx context.Context, exhibitionID string) (*util.Response, error) {
+	return c.get(ctx, c.getKeyByID(exhibitionID))
+}
 
-	return &exhibition, nil
+func (c *Cache) GetExpanded(ctx context.Context, exhibitionID string, queryString string) (*util.Response, error) {
+	return c.get(ctx, c.getKeyByExpand(exhibitionID, queryString))
 }
 
-func (c *Cache) GetMany(ctx context.Context, queryString string) ([]*model.Exhibition, error) {
-	key := c.getKeyByQuery(queryString)
-	val, err := c.client.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
+func (c *Cache) GetMany(ctx context.Context, queryString string) (*util.Response, error) {
+	return c.get(ctx, c.getKeyByQuery(queryString))
+}
 
-	var exhibitions []*model.Exhibition
-	err = json.Unmarshal([]byte(val), &exhibitions)
-	if err != nil {
-		return nil, err
-	}
+func (c *Cache) GetArtworks(ctx context.Context, exhibitionID string, queryString string) (*util.Response, error) {
+	return c.get(ctx, c.getKeyByArtworks(exhibitionID, queryString))
+}
 
-	return exhibitions, nil
+func (c *Cache) GetArtists(ctx context.Context, exhibitionID string, queryString string) (*util.Response, error) {
+	return c.get(ctx, c.getKeyByArtists(exhibitionID, queryString))
 }
 
-func (c *Cache) GetArtworks(ctx context.Context, exhibitionID string, queryString string) ([]*model.Artwork, error) {
-	key := c.getKeyByArtworks(exhibitionID, queryString)
-	val, err := c.client.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
+func (c *Cache) Set(ctx context.Context, exhibitionID string, res *util.Response) error {
+	return c.set(ctx, c.getKeyByID(exhibitionID), res)
+}
 
-	var artworks []*model.Artwork
-	err = json.Unmarshal([]byte(val), &artworks)
-	if err != nil {
-		return nil, err
-	}
+func (c *Cache) SetExpanded(ctx context.Context, exhibitionID string, queryString string, res *util.Response) error {
+	return c.set(ctx, c.getKeyByExpand(exhibitionID, queryString), res)
+}
 
-	return artworks, nil
+func (c *Cache) SetMany(ctx context.Context, queryString string, res *util.Response) error {
+	return c.set(ctx, c.getKeyByQuery(queryString), res)
 }
 
-func (c *Cache) GetArtists(ctx context.Context, exhibitionID string, queryString string) ([]*model.Artist, error) {
-	key := c.getKeyByArtists(exhibitionID, queryString)
-	val, err := c.client.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return nil, nil
-	} else if err != nil {
-		return nil, err
-	}
+func (c *Cache) SetArtworks(ctx context.Context, exhibitionID string, queryString string, res *util.Response) error {
+	return c.set(ctx, c.getKeyByArtworks(exhibitionID, queryString), res)
+}
 
-	var artists []*model.Artist
-	err = json.Unmarshal([]byte(val), &artists)
-	if err != nil {
-		return nil, err
-	}
+func (c *Cache) SetArtists(ctx context.Context, exhibitionID string, queryString string, res *util.Response) error {
+	return c.set(ctx, c.getKeyByArtists(exhibitionID, queryString), res)
+}
 
-	return artists, nil
+// Invalidate removes the cached exhibition with and without its relations
+// expanded, its cached artworks and artists and every cached exhibition listing
+func (c *Cache) Invalidate(ctx context.Context, exhibitionID string) error {
+	keys := []string{c.getKeyByID(exhibitionID)}
+	return c.invalidate(ctx, keys,
+		c.getKeyPatternByQuery(),
+		c.getKeyPatternByExpand(exhibitionID),
+		c.getKeyPatternByArtworks(exhibitionID),
+		c.getKeyPatternByArtists(exhibitionID),
+	)
 }
 
-func (c *Cache) Set(ctx context.Context, exhibitionID string, exhibition *model.Exhibition) error {
-	exhibitionJson, err := json.Marshal(exhibition)
-	if err != nil {
-		return err
-	}
+// InvalidateContents removes the cached artworks and artists of every
+// exhibition, expanded or listed, which a write to an artwork or artist makes stale
+func (c *Cache) InvalidateContents(ctx context.Context) error {
+	return c.invalidate(ctx, nil,
+		c.getKeyPatternByExpand("*"),
+		c.getKeyPatternByArtworks("*"),
+		c.getKeyPatternByArtists("*"),
+	)
+}
 
-	key := c.getKeyByID(exhibitionID)
-	err = c.client.Set(ctx, key, exhibitionJson, c.expiration).Err()
-	return err
+// InvalidateAll removes everything cached about the exhibitions, for writes
+// that take artworks or artists out of them
+func (c *Cache) InvalidateAll(ctx context.Context) error {
+	return c.invalidate(ctx, nil, c.getKeyPatternByID(), c.getKeyPatternByQuery())
 }
 
-func (c *Cache) SetMany(ctx context.Context, queryString string, exhibitions []*model.Exhibition) error {
-	exhibitionsJson, err := json.Marshal(exhibitions)
-	if err != nil {
-		return err
+// invalidate removes the keys and every key matching one of the patterns
+func (c *Cache) invalidate(ctx context.Context, keys []string, patterns ...string) error {
+	for _, pattern := range patterns {
+		iter := c.client.Scan(ctx, 0, pattern, 0).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
 	}
 
-	key := c.getKeyByQuery(queryString)
-	err = c.client.Set(ctx, key, exhibitionsJson, c.expiration).Err()
-	return err
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.client.Del(ctx, keys...).Err()
 }
 
-func (c *Cache) SetArtworks(ctx context.Context, exhibitionID string, queryString string, artworks []*model.Artwork) error {
-	artworksJson, err := json.Marshal(artworks)
+func (c *Cache) get(ctx context.Context, key string) (*util.Response, error) {
+	val, err := c.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var res util.Response
+	err = json.Unmarshal([]byte(val), &res)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	key := c.getKeyByArtworks(exhibitionID, queryString)
-	err = c.client.Set(ctx, key, artworksJson, c.expiration).Err()
-	return err
+	return &res, nil
 }
 
-func (c *Cache) SetArtists(ctx context.Context, exhibitionID string, queryString string, artworks []*model.Artist) error {
-	artistJson, err := json.Marshal(artworks)
+// set caches the response body along with its ETag
+func (c *Cache) set(ctx context.Context, key string, res *util.Response) error {
+	resJson, err := json.Marshal(res)
 	if err != nil {
 		return err
 	}
 
-	key := c.getKeyByArtists(exhibitionID, queryString)
-	err = c.client.Set(ctx, key, artistJson, c.expiration).Err()
-	return err
+	return c.client.Set(ctx, key, resJson, c.expiration).Err()
 }
 
 func (c *Cache) getKeyByID(exhibitionID string) string {
@@ -148,6 +145,10 @@ func (c *Cache) getKeyByQuery(queryString string) string {
 	return fmt.Sprintf("%s?%s", c.namespace, queryString)
 }
 
+func (c *Cache) getKeyByExpand(exhibitionID string, queryString string) string {
+	return fmt.Sprintf("%s:%s:%s?%s", c.namespace, exhibitionID, "expand", queryString)
+}
+
 func (c *Cache) getKeyByArtworks(exhibitionID string, queryString string) string {
 	return fmt.Sprintf("%s:%s:%s?%s", c.namespace, exhibitionID, "artwork", queryString)
 }
@@ -155,3 +156,23 @@ func (c *Cache) getKeyByArtworks(exhibitionID string, queryString string) string
 func (c *Cache) getKeyByArtists(exhibitionID string, queryString string) string {
 	return fmt.Sprintf("%s:%s:%s?%s", c.namespace, exhibitionID, "artist", queryString)
 }
+
+func (c *Cache) getKeyPatternByQuery() string {
+	return fmt.Sprintf("%s\\?*", c.namespace)
+}
+
+func (c *Cache) getKeyPatternByExpand(exhibitionID string) string {
+	return fmt.Sprintf("%s:%s:%s\\?*", c.namespace, exhibitionID, "expand")
+}
+
+func (c *Cache) getKeyPatternByArtworks(exhibitionID string) string {
+	return fmt.Sprintf("%s:%s:%s\\?*", c.namespace, exhibitionID, "artwork")
+}
+
+func (c *Cache) getKeyPatternByArtists(exhibitionID string) string {
+	return fmt.Sprintf("%s:%s:%s\\?*", c.namespace, exhibitionID, "artist")
+}
+
+func (c *Cache) getKeyPatternByID() string {
+	return fmt.Sprintf("%s:*", c.namespace)
+}
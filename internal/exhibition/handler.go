@@ -1,32 +1,52 @@
 package exhibition
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/iamnotrodger/art-house-api/internal/model"
 	"github.com/iamnotrodger/art-house-api/internal/query"
+	"github.com/iamnotrodger/art-house-api/internal/revision"
 	"github.com/iamnotrodger/art-house-api/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// curationRequest is the body of the requests that change an exhibition's artworks
+type curationRequest struct {
+	ArtworkIDs []primitive.ObjectID `json:"artwork_ids"`
+	Position   *int                 `json:"position,omitempty"`
+}
+
 type Handler struct {
-	store *Store
-	cache *Cache
+	store     *Store
+	cache     *Cache
+	revisions *revision.Store
 }
 
-func NewHandler(store *Store, cache *Cache) *Handler {
+func NewHandler(store *Store, cache *Cache, revisions *revision.Store) *Handler {
 	return &Handler{
-		store: store,
-		cache: cache,
+		store:     store,
+		cache:     cache,
+		revisions: revisions,
 	}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/exhibitions", h.GetMany).Methods("GET")
 	router.HandleFunc("/api/exhibitions/{id}", h.Get).Methods("GET")
+	router.HandleFunc("/api/exhibitions/{id}", h.Delete).Methods("DELETE")
 	router.HandleFunc("/api/exhibitions/{id}/artworks", h.GetArtworks).Methods("GET")
+	router.HandleFunc("/api/exhibitions/{id}/artworks", h.AddArtworks).Methods("POST")
+	router.HandleFunc("/api/exhibitions/{id}/artworks", h.ReorderArtworks).Methods("PUT")
+	router.HandleFunc("/api/exhibitions/{id}/artworks/{artworkID}", h.RemoveArtwork).Methods("DELETE")
 	router.HandleFunc("/api/exhibitions/{id}/artists", h.GetArtists).Methods("GET")
+	router.HandleFunc("/api/admin/trash/exhibitions", h.GetDeleted).Methods("GET")
+	router.HandleFunc("/api/admin/trash/exhibitions/{id}/restore", h.Restore).Methods("POST")
+	router.HandleFunc("/api/admin/trash/exhibitions/{id}", h.Purge).Methods("DELETE")
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
@@ -35,51 +55,114 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	exhibitionID := params["id"]
 
-	exhibition, err := h.cache.Get(r.Context(), exhibitionID)
+	expand, err := query.NewExhibitionExpand(r.URL.Query())
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, expand); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if !expand.IsEmpty() {
+		h.getExpanded(w, r, exhibitionID, expand)
+		return
+	}
+
+	res, err := h.cache.Get(r.Context(), exhibitionID)
+	if err != nil {
+		log.Println(err)
+	} else if res != nil {
+		res.Write(w, r, http.StatusOK)
+		return
+	}
+
+	exhibition, err := h.store.Find(r.Context(), exhibitionID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	res, err = util.NewVersionedResponse(exhibition, exhibition.Version)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	err = h.cache.Set(r.Context(), exhibitionID, res)
+	if err != nil {
+		log.Println(err)
+	}
+
+	res.Write(w, r, http.StatusOK)
+}
+
+// getExpanded responds with the exhibition and the summaries of its expanded
+// relations, which are cached apart from the exhibition on its own
+func (h *Handler) getExpanded(w http.ResponseWriter, r *http.Request, exhibitionID string, expand *query.ExhibitionExpand) {
+	queryString := r.URL.RawQuery
+	res, err := h.cache.GetExpanded(r.Context(), exhibitionID, queryString)
 	if err != nil {
 		log.Println(err)
-	} else if exhibition != nil {
-		json.NewEncoder(w).Encode(exhibition)
+	} else if res != nil {
+		res.Write(w, r, http.StatusOK)
 		return
 	}
 
-	exhibition, err = h.store.Find(r.Context(), exhibitionID)
+	exhibition, err := h.store.FindExpanded(r.Context(), exhibitionID, expand)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	res, err = util.NewVersionedResponse(exhibition, exhibition.Version)
 	if err != nil {
 		util.HandleError(w, err)
 		return
 	}
-	err = h.cache.Set(r.Context(), exhibitionID, exhibition)
+	err = h.cache.SetExpanded(r.Context(), exhibitionID, queryString, res)
 	if err != nil {
 		log.Println(err)
 	}
 
-	json.NewEncoder(w).Encode(exhibition)
+	res.Write(w, r, http.StatusOK)
 }
 
 func (h *Handler) GetMany(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	queryParams, err := query.NewExhibitionQuery(r.URL.Query())
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, queryParams); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
 	queryString := r.URL.RawQuery
-	exhibitions, err := h.cache.GetMany(r.Context(), queryString)
+	res, err := h.cache.GetMany(r.Context(), queryString)
 	if err != nil {
 		log.Println(err)
-	} else if exhibitions != nil {
-		json.NewEncoder(w).Encode(exhibitions)
+	} else if res != nil {
+		res.Write(w, r, http.StatusOK)
 		return
 	}
 
-	queryParams := query.NewExhibitionQuery(r.URL.Query())
-	exhibitions, err = h.store.FindMany(r.Context(), queryParams)
+	exhibitions, page, err := h.store.FindMany(r.Context(), queryParams)
 	if err != nil {
 		util.HandleError(w, err)
 		return
 	}
-	err = h.cache.SetMany(r.Context(), queryString, exhibitions)
+	res, err = util.NewPageResponse(r, exhibitions, page)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	err = h.cache.SetMany(r.Context(), queryString, res)
 	if err != nil {
 		log.Println(err)
 	}
 
-	json.NewEncoder(w).Encode(exhibitions)
+	res.Write(w, r, http.StatusOK)
 }
 
 func (h *Handler) GetArtworks(w http.ResponseWriter, r *http.Request) {
@@ -88,27 +171,41 @@ func (h *Handler) GetArtworks(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	exhibitionID := params["id"]
 
+	queryParams, err := query.NewArtworkQuery(r.URL.Query(), query.CuratedSort)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, queryParams); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
 	queryString := r.URL.RawQuery
-	artworks, err := h.cache.GetArtworks(r.Context(), exhibitionID, queryString)
+	res, err := h.cache.GetArtworks(r.Context(), exhibitionID, queryString)
 	if err != nil {
 		log.Println(err)
-	} else if artworks != nil {
-		json.NewEncoder(w).Encode(artworks)
+	} else if res != nil {
+		res.Write(w, r, http.StatusOK)
 		return
 	}
 
-	queryParams := query.NewArtworkQuery(r.URL.Query())
-	artworks, err = h.store.FindArtworks(r.Context(), exhibitionID, queryParams)
+	artworks, page, err := h.store.FindArtworks(r.Context(), exhibitionID, queryParams)
 	if err != nil {
 		util.HandleError(w, err)
 		return
 	}
-	err = h.cache.SetArtworks(r.Context(), queryString, exhibitionID, artworks)
+	res, err = util.NewPageResponse(r, artworks, page)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	err = h.cache.SetArtworks(r.Context(), exhibitionID, queryString, res)
 	if err != nil {
 		log.Println(err)
 	}
 
-	json.NewEncoder(w).Encode(artworks)
+	res.Write(w, r, http.StatusOK)
 }
 
 func (h *Handler) GetArtists(w http.ResponseWriter, r *http.Request) {
@@ -117,25 +214,264 @@ func (h *Handler) GetArtists(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	exhibitionID := params["id"]
 
+	queryParams, err := query.NewArtistQuery(r.URL.Query())
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, queryParams); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
 	queryString := r.URL.RawQuery
-	artists, err := h.cache.GetArtists(r.Context(), exhibitionID, queryString)
+	res, err := h.cache.GetArtists(r.Context(), exhibitionID, queryString)
 	if err != nil {
 		log.Println(err)
-	} else if artists != nil {
-		json.NewEncoder(w).Encode(artists)
+	} else if res != nil {
+		res.Write(w, r, http.StatusOK)
 		return
 	}
 
-	queryParams := query.NewArtistQuery(r.URL.Query())
-	artists, err = h.store.FindArtists(r.Context(), exhibitionID, queryParams)
+	artists, page, err := h.store.FindArtists(r.Context(), exhibitionID, queryParams)
 	if err != nil {
 		util.HandleError(w, err)
 		return
 	}
-	err = h.cache.SetArtists(r.Context(), queryString, exhibitionID, artists)
+	res, err = util.NewPageResponse(r, artists, page)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	err = h.cache.SetArtists(r.Context(), exhibitionID, queryString, res)
 	if err != nil {
 		log.Println(err)
 	}
 
-	json.NewEncoder(w).Encode(artists)
+	res.Write(w, r, http.StatusOK)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	exhibitionID := params["id"]
+
+	doc, err := h.store.Delete(r.Context(), exhibitionID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionDelete)
+
+	err = h.cache.Invalidate(r.Context(), exhibitionID)
+	if err != nil {
+		log.Println(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetDeleted(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	queryParams, err := query.NewExhibitionQuery(r.URL.Query(), query.DeletedSort)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, queryParams); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	exhibitions, page, err := h.store.FindDeleted(r.Context(), queryParams)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	util.RespondWithPage(w, r, http.StatusOK, exhibitions, page)
+}
+
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	exhibitionID := params["id"]
+
+	doc, err := h.store.Restore(r.Context(), exhibitionID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionRestore)
+
+	err = h.cache.Invalidate(r.Context(), exhibitionID)
+	if err != nil {
+		log.Println(err)
+	}
+
+	exhibition, err := h.store.Find(r.Context(), exhibitionID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	res, err := util.NewVersionedResponse(exhibition, exhibition.Version)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	res.Write(w, r, http.StatusOK)
+}
+
+func (h *Handler) Purge(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	exhibitionID := params["id"]
+
+	doc, err := h.store.Purge(r.Context(), exhibitionID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionPurge)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) AddArtworks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	exhibitionID := params["id"]
+
+	version, err := util.GetIfMatch(r, func() (int64, error) {
+		return h.store.Version(r.Context(), exhibitionID)
+	})
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	var body curationRequest
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(body.ArtworkIDs) == 0 {
+		util.HandleError(w, &model.ValidationError{Field: "artwork_ids", Message: "is required"})
+		return
+	}
+
+	doc, err := h.store.AddArtworks(r.Context(), exhibitionID, body.ArtworkIDs, body.Position, version)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionUpdate)
+
+	h.respondWithArtworks(w, r, exhibitionID, version+1)
+}
+
+func (h *Handler) ReorderArtworks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	exhibitionID := params["id"]
+
+	version, err := util.GetIfMatch(r, func() (int64, error) {
+		return h.store.Version(r.Context(), exhibitionID)
+	})
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	var body curationRequest
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	doc, err := h.store.ReorderArtworks(r.Context(), exhibitionID, body.ArtworkIDs, version)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionUpdate)
+
+	h.respondWithArtworks(w, r, exhibitionID, version+1)
+}
+
+func (h *Handler) RemoveArtwork(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	exhibitionID := params["id"]
+	artworkID := params["artworkID"]
+
+	version, err := util.GetIfMatch(r, func() (int64, error) {
+		return h.store.Version(r.Context(), exhibitionID)
+	})
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	doc, err := h.store.RemoveArtwork(r.Context(), exhibitionID, artworkID, version)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	h.recordRevision(r, doc, model.RevisionUpdate)
+
+	h.respondWithArtworks(w, r, exhibitionID, version+1)
+}
+
+// SyncReverted brings the exhibition's artists back in line with its artworks
+// after it is reverted, and removes its cached entries
+func (h *Handler) SyncReverted(ctx context.Context, exhibitionID string) error {
+	err := h.store.SyncArtists(ctx, exhibitionID)
+	if err != nil {
+		return err
+	}
+
+	err = h.cache.Invalidate(ctx, exhibitionID)
+	if err != nil {
+		log.Println(err)
+	}
+
+	return nil
+}
+
+// respondWithArtworks invalidates the cached exhibition after its artworks change and responds with the curated
+// artworks, the ETag is the exhibition's new version so that curation requests can be chained
+func (h *Handler) respondWithArtworks(w http.ResponseWriter, r *http.Request, exhibitionID string, version int64) {
+	err := h.cache.Invalidate(r.Context(), exhibitionID)
+	if err != nil {
+		log.Println(err)
+	}
+
+	artworks, _, err := h.store.FindArtworks(r.Context(), exhibitionID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	res, err := util.NewVersionedResponse(artworks, version)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	res.Write(w, r, http.StatusOK)
+}
+
+// recordRevision snapshots the exhibition as a write left it, a failure does not fail the write
+func (h *Handler) recordRevision(r *http.Request, document bson.M, action string) {
+	_, err := h.revisions.Record(r.Context(), "exhibitions", action, revision.GetAuthor(r), document)
+	if err != nil {
+		log.Println(err)
+	}
 }
@@ -2,21 +2,53 @@ package exhibition
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/iamnotrodger/art-house-api/internal/model"
 	"github.com/iamnotrodger/art-house-api/internal/query"
+	"github.com/iamnotrodger/art-house-api/internal/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var errExhibitionModified = &model.PreconditionError{Message: "exhibition has been modified"}
+
+var (
+	// matchArtworks keeps the artworks of the exhibition in a lookup
+	matchArtworks = bson.D{{
+		Key: "$match",
+		Value: bson.D{{
+			Key: "$expr",
+			Value: bson.D{{
+				Key:   "$in",
+				Value: bson.A{"$_id", "$$artwork_ids"},
+			}},
+		}},
+	}}
+	// matchArtists keeps the artists of the exhibition in a lookup
+	matchArtists = bson.D{{
+		Key: "$match",
+		Value: bson.D{{
+			Key: "$expr",
+			Value: bson.D{{
+				Key:   "$in",
+				Value: bson.A{"$_id", "$$artist_ids"},
+			}},
+		}},
+	}}
+)
+
 type Store struct {
+	db         *mongo.Database
 	collection *mongo.Collection
 }
 
 func NewStore(db *mongo.Database) *Store {
 	return &Store{
+		db:         db,
 		collection: db.Collection("exhibitions"),
 	}
 }
@@ -24,17 +56,17 @@ func NewStore(db *mongo.Database) *Store {
 func (s *Store) Find(ctx context.Context, exhibitionID string) (*model.Exhibition, error) {
 	id, err := primitive.ObjectIDFromHex(exhibitionID)
 	if err != nil {
-		return nil, primitive.ErrInvalidHex
+		return nil, model.ErrInvalidID
 	}
 
-	cursor, err := s.collection.Find(ctx, bson.M{"_id": id})
+	cursor, err := s.collection.Find(ctx, bson.M{"_id": id, "deleted_at": nil})
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
 	if cursor.RemainingBatchLength() < 1 {
-		return nil, mongo.ErrNoDocuments
+		return nil, model.ErrNotFound
 	}
 
 	var exhibition model.Exhibition
@@ -49,9 +81,58 @@ func (s *Store) Find(ctx context.Context, exhibitionID string) (*model.Exhibitio
 	return &exhibition, nil
 }
 
-func (s *Store) FindMany(ctx context.Context, queryParam ...query.QueryParams) ([]*model.Exhibition, error) {
+// FindExpanded returns the exhibition with the relations of expand embedded
+// into it, looked up like the lists of FindArtworks and FindArtists
+func (s *Store) FindExpanded(ctx context.Context, exhibitionID string, expand *query.ExhibitionExpand) (*model.Exhibition, error) {
+	id, err := primitive.ObjectIDFromHex(exhibitionID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	match := bson.D{{Key: "$match", Value: bson.M{"_id": id, "deleted_at": nil}}}
+	pipeline := mongo.Pipeline{match}
+	if artworkQuery := expand.GetArtworkQuery(); artworkQuery != nil {
+		err = query.FindExhibitionArtworks(ctx, s.db, artworkQuery)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, artworksLookup(artworkQuery))
+	}
+	if artistQuery := expand.GetArtistQuery(); artistQuery != nil {
+		pipeline = append(pipeline, artistsLookup(artistQuery))
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if cursor.RemainingBatchLength() < 1 {
+		return nil, model.ErrNotFound
+	}
+
+	var exhibition model.Exhibition
+	cursor.Next(ctx)
+	cursor.Decode(&exhibition)
+	if err = cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	model.SortImages(exhibition.Images)
+	for _, artwork := range exhibition.Artworks {
+		model.SortImages(artwork.Images)
+	}
+	for _, artist := range exhibition.Artists {
+		model.SortImages(artist.Images)
+	}
+
+	return &exhibition, nil
+}
+
+func (s *Store) FindMany(ctx context.Context, queryParam ...query.QueryParams) ([]*model.Exhibition, *model.Page, error) {
 	var opts *options.FindOptions
-	filter := bson.D{}
+	filter := query.NotDeletedFilter
 
 	if len(queryParam) > 0 {
 		filter = queryParam[0].GetFilter()
@@ -60,157 +141,741 @@ func (s *Store) FindMany(ctx context.Context, queryParam ...query.QueryParams) (
 
 	cursor, err := s.collection.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer cursor.Close(ctx)
 
-	exhibitions := []*model.Exhibition{}
-	for cursor.Next(ctx) {
-		var exhibit model.Exhibition
-		cursor.Decode(&exhibit)
-		exhibitions = append(exhibitions, &exhibit)
+	exhibitions, last, err := decodeExhibitions(ctx, cursor)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	if err = cursor.Err(); err != nil {
-		return nil, err
+	var total int64
+	if len(queryParam) > 0 {
+		total, err = query.Count(ctx, s.collection, queryParam[0].GetCountPipeline())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	page, err := query.NewPage(len(exhibitions), last, total, queryParam...)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	for _, exhibit := range exhibitions {
 		model.SortImages(exhibit.Images)
 	}
 
-	return exhibitions, nil
+	return exhibitions, page, nil
 }
 
-func (s *Store) FindArtworks(ctx context.Context, exhibitionID string, queryParam ...query.QueryParams) ([]*model.Artwork, error) {
+// FindArtworks returns the artworks of the exhibition in their curated order,
+// unless the query sorts them. The artworks carry their position in the
+// curation, so that a query can sort and page on it.
+func (s *Store) FindArtworks(ctx context.Context, exhibitionID string, queryParam ...query.QueryParams) ([]*model.Artwork, *model.Page, error) {
 	id, err := primitive.ObjectIDFromHex(exhibitionID)
 	if err != nil {
-		return nil, primitive.ErrInvalidHex
+		return nil, nil, model.ErrInvalidID
 	}
 
-	match := bson.D{{Key: "$match", Value: bson.M{"_id": id}}}
-	matchArtworks := bson.D{{
-		Key: "$match",
+	artworkIDs, err := s.findReferences(ctx, id, "artwork_ids")
+	if err != nil {
+		return nil, nil, err
+	}
+	err = query.FindExhibitionArtworks(ctx, s.db, queryParam...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byExhibition := bson.D{{Key: "$match", Value: bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: artworkIDs}}}}}}
+	position := bson.D{{
+		Key: "$addFields",
 		Value: bson.D{{
-			Key: "$expr",
+			Key: "position",
 			Value: bson.D{{
-				Key:   "$in",
-				Value: bson.A{"$_id", "$$artwork_ids"},
+				Key:   "$indexOfArray",
+				Value: bson.A{bson.D{{Key: "$literal", Value: artworkIDs}}, "$_id"},
 			}},
 		}},
 	}}
+	sortByPosition := bson.D{{Key: "$sort", Value: bson.D{{Key: "position", Value: 1}}}}
 
-	lookupPipeline := bson.A{matchArtworks}
+	pipeline := mongo.Pipeline{byExhibition, query.NotDeletedStage, position, sortByPosition}
 	if len(queryParam) > 0 {
-		for _, queryOpts := range queryParam[0].GetPipeline() {
-			lookupPipeline = append(lookupPipeline, queryOpts)
+		pipeline = matchExhibition(queryParam[0].GetPipeline(), byExhibition, position)
+	}
+	pipeline = query.JoinArtist(pipeline)
+
+	artworksCollection := s.db.Collection("artworks")
+	cursor, err := artworksCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var last bson.Raw
+	artworks := []*model.Artwork{}
+	for cursor.Next(ctx) {
+		var artwork model.Artwork
+		if err = cursor.Decode(&artwork); err != nil {
+			err = fmt.Errorf("failed to unmarshal artworks: %w", err)
+			return nil, nil, err
 		}
+		artworks = append(artworks, &artwork)
+		last = cursor.Current
+	}
+	if err = cursor.Err(); err != nil {
+		return nil, nil, err
 	}
-	lookupPipeline = append(lookupPipeline, query.ArtworkLookupStage, query.ArtworkUnwindStage)
 
-	lookup := bson.D{{
-		Key: "$lookup",
+	var total int64
+	if len(queryParam) > 0 {
+		total, err = query.Count(ctx, artworksCollection, matchExhibition(queryParam[0].GetCountPipeline(), byExhibition, position))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	page, err := query.NewPage(len(artworks), last, total, queryParam...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, artwork := range artworks {
+		model.SortImages(artwork.Images)
+		// the artist is left out when the query selects fields without it
+		if artwork.Artist != nil {
+			model.SortImages(artwork.Artist.Images)
+		}
+	}
+
+	return artworks, page, nil
+}
+
+func (s *Store) FindArtists(ctx context.Context, exhibitionID string, queryParam ...query.QueryParams) ([]*model.Artist, *model.Page, error) {
+	id, err := primitive.ObjectIDFromHex(exhibitionID)
+	if err != nil {
+		return nil, nil, model.ErrInvalidID
+	}
+
+	artistIDs, err := s.findReferences(ctx, id, "artist_ids")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byExhibition := bson.D{{Key: "$match", Value: bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: artistIDs}}}}}}
+
+	pipeline := mongo.Pipeline{byExhibition, query.NotDeletedStage}
+	if len(queryParam) > 0 {
+		pipeline = matchExhibition(queryParam[0].GetPipeline(), byExhibition)
+	}
+
+	artistsCollection := s.db.Collection("artists")
+	cursor, err := artistsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var last bson.Raw
+	artists := []*model.Artist{}
+	for cursor.Next(ctx) {
+		var artist model.Artist
+		if err = cursor.Decode(&artist); err != nil {
+			err = fmt.Errorf("failed to unmarshal artists: %w", err)
+			return nil, nil, err
+		}
+		artists = append(artists, &artist)
+		last = cursor.Current
+	}
+	if err = cursor.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var total int64
+	if len(queryParam) > 0 {
+		total, err = query.Count(ctx, artistsCollection, matchExhibition(queryParam[0].GetCountPipeline(), byExhibition))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	page, err := query.NewPage(len(artists), last, total, queryParam...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, artist := range artists {
+		model.SortImages(artist.Images)
+	}
+
+	return artists, page, nil
+}
+
+// findReferences returns the IDs that the exhibition holds in a field, such as
+// the IDs of its artworks
+func (s *Store) findReferences(ctx context.Context, id primitive.ObjectID, field string) ([]primitive.ObjectID, error) {
+	opts := options.FindOne().SetProjection(bson.M{field: 1})
+	singleRes := s.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": nil}, opts)
+	if err := singleRes.Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, model.ErrNotFound
+		}
+		return nil, err
+	}
+
+	doc, err := singleRes.DecodeBytes()
+	if err != nil {
+		err = fmt.Errorf("error decoding exhibition: %w", err)
+		return nil, err
+	}
+
+	ids := []primitive.ObjectID{}
+	array, ok := doc.Lookup(field).ArrayOK()
+	if !ok {
+		return ids, nil
+	}
+	values, err := array.Values()
+	if err != nil {
+		return nil, fmt.Errorf("error decoding exhibition: %w", err)
+	}
+	for _, value := range values {
+		if id, ok := value.ObjectIDOK(); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *Store) InsertMany(ctx context.Context, exhibitions []*model.Exhibition) error {
+	var docs []interface{}
+
+	for _, exhibit := range exhibitions {
+		docs = append(docs, exhibit.ConvertToBson())
+	}
+
+	_, err := s.collection.InsertMany(ctx, docs)
+	return err
+}
+
+// Stream decodes every exhibition one at a time from the cursor and passes it to
+// fn, the exhibition's artists and artworks only hold their IDs
+func (s *Store) Stream(ctx context.Context, fn func(*model.Exhibition) error) error {
+	references := bson.D{{
+		Key: "$addFields",
 		Value: bson.D{
-			{Key: "from", Value: "artworks"},
-			{Key: "let", Value: bson.D{{Key: "artwork_ids", Value: "$artwork_ids"}}},
-			{Key: "pipeline", Value: lookupPipeline},
-			{Key: "as", Value: "artworks"},
+			{Key: "artists", Value: referenceIDs("$artist_ids")},
+			{Key: "artworks", Value: referenceIDs("$artwork_ids")},
 		},
 	}}
 
-	pipeline := mongo.Pipeline{match, lookup}
+	pipeline := mongo.Pipeline{query.NotDeletedStage, references}
 	cursor, err := s.collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer cursor.Close(ctx)
 
-	if cursor.RemainingBatchLength() < 1 {
-		return nil, mongo.ErrNoDocuments
+	for cursor.Next(ctx) {
+		var exhibition model.Exhibition
+		err = cursor.Decode(&exhibition)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal exhibition: %w", err)
+		}
+
+		model.SortImages(exhibition.Images)
+		if err = fn(&exhibition); err != nil {
+			return err
+		}
 	}
 
-	exhibition := model.Exhibition{}
-	cursor.Next(ctx)
-	cursor.Decode(&exhibition)
-	if err = cursor.Err(); err != nil {
+	return cursor.Err()
+}
+
+// Version returns the version the exhibition is stored at
+func (s *Store) Version(ctx context.Context, exhibitionID string) (int64, error) {
+	return util.FindVersion(ctx, s.collection, exhibitionID)
+}
+
+// Delete moves the exhibition to the trash
+func (s *Store) Delete(ctx context.Context, exhibitionID string) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(exhibitionID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	update := bson.M{"$set": bson.M{"deleted_at": time.Now()}, "$inc": bson.M{"version": 1}}
+	doc, err := util.UpdateAndReturn(ctx, s.collection, bson.M{"_id": id, "deleted_at": nil}, update)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrNotFound
+	}
+	return doc, err
+}
+
+// FindDeleted returns the exhibitions in the trash, most recently deleted first
+func (s *Store) FindDeleted(ctx context.Context, queryParam ...query.QueryParams) ([]*model.Exhibition, *model.Page, error) {
+	opts := options.Find().SetSort(bson.D{query.DeletedSort})
+	filter := append(bson.D{}, query.DeletedFilter...)
+	if len(queryParam) > 0 {
+		opts = queryParam[0].GetFindOptions()
+		filter = append(filter, queryParam[0].GetCursorFilter()...)
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	exhibitions, last, err := decodeExhibitions(ctx, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	var total int64
+	if len(queryParam) > 0 {
+		total, err = query.Count(ctx, s.collection, query.CountPipeline(query.DeletedFilter))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	page, err := query.NewPage(len(exhibitions), last, total, queryParam...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, exhibit := range exhibitions {
+		model.SortImages(exhibit.Images)
+	}
+
+	return exhibitions, page, nil
+}
+
+// Restore moves the exhibition out of the trash
+func (s *Store) Restore(ctx context.Context, exhibitionID string) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(exhibitionID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	update := bson.M{"$unset": bson.M{"deleted_at": ""}, "$inc": bson.M{"version": 1}}
+	doc, err := util.UpdateAndReturn(ctx, s.collection, bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}}, update)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrNotFound
+	}
+	return doc, err
+}
+
+// Purge permanently removes the exhibition from the trash
+func (s *Store) Purge(ctx context.Context, exhibitionID string) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(exhibitionID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	doc, err := util.DeleteAndReturn(ctx, s.collection, bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}})
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrNotFound
+	}
+	return doc, err
+}
+
+// UpsertMany replaces the exhibitions matching by ID, or by name when an
+// exhibition has no ID, and inserts the ones that do not exist yet
+func (s *Store) UpsertMany(ctx context.Context, exhibitions []*model.Exhibition) (*mongo.BulkWriteResult, error) {
+	writes := []mongo.WriteModel{}
+
+	for _, exhibit := range exhibitions {
+		filter := bson.M{"_id": exhibit.ID}
+		if exhibit.ID.IsZero() {
+			filter = bson.M{"name": exhibit.Name}
+		}
+
+		model.SortImages(exhibit.Images)
+		write := mongo.NewReplaceOneModel().
+			SetFilter(filter).
+			SetReplacement(exhibit.ConvertToBson()).
+			SetUpsert(true)
+		writes = append(writes, write)
+	}
+
+	if len(writes) == 0 {
+		return &mongo.BulkWriteResult{}, nil
+	}
+	return s.collection.BulkWrite(ctx, writes)
+}
+
+// AddArtworks inserts the artworks into the exhibition at the given position, or
+// appends them when position is nil. Artworks already in the exhibition are skipped.
+func (s *Store) AddArtworks(ctx context.Context, exhibitionID string, artworkIDs []primitive.ObjectID, position *int, version int64) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(exhibitionID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	current, err := s.findArtworkIDs(ctx, id, version)
+	if err != nil {
 		return nil, err
 	}
 
-	for _, artwork := range exhibition.Artworks {
-		model.SortImages(artwork.Images)
-		model.SortImages(artwork.Artist.Images)
+	index := len(current)
+	if position != nil {
+		if *position < 0 || *position > len(current) {
+			return nil, &model.ValidationError{Field: "position", Message: "is out of range"}
+		}
+		index = *position
+	}
+
+	added := []primitive.ObjectID{}
+	for _, artworkID := range artworkIDs {
+		if indexOf(current, artworkID) < 0 && indexOf(added, artworkID) < 0 {
+			added = append(added, artworkID)
+		}
 	}
 
-	return exhibition.Artworks, nil
+	count, err := s.db.Collection("artworks").CountDocuments(ctx, bson.M{"_id": bson.M{"$in": added}, "deleted_at": nil})
+	if err != nil {
+		return nil, err
+	}
+	if count < int64(len(added)) {
+		return nil, &model.ValidationError{Field: "artwork_ids", Message: "contains artworks that do not exist"}
+	}
+
+	artworks := make([]primitive.ObjectID, 0, len(current)+len(added))
+	artworks = append(artworks, current[:index]...)
+	artworks = append(artworks, added...)
+	artworks = append(artworks, current[index:]...)
+
+	return s.setArtworks(ctx, id, artworks, version)
 }
 
-func (s *Store) FindArtists(ctx context.Context, exhibitionID string, queryParam ...query.QueryParams) ([]*model.Artist, error) {
+func (s *Store) RemoveArtwork(ctx context.Context, exhibitionID string, artworkID string, version int64) (bson.M, error) {
 	id, err := primitive.ObjectIDFromHex(exhibitionID)
 	if err != nil {
-		return nil, primitive.ErrInvalidHex
+		return nil, model.ErrInvalidID
+	}
+	removedID, err := primitive.ObjectIDFromHex(artworkID)
+	if err != nil {
+		return nil, model.ErrInvalidID
 	}
 
-	match := bson.D{{Key: "$match", Value: bson.M{"_id": id}}}
-	matchArtists := bson.D{{
-		Key: "$match",
-		Value: bson.D{{
-			Key: "$expr",
-			Value: bson.D{{
-				Key:   "$in",
-				Value: bson.A{"$_id", "$$artist_ids"},
-			}},
+	current, err := s.findArtworkIDs(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	index := indexOf(current, removedID)
+	if index < 0 {
+		return nil, model.ErrNotFound
+	}
+
+	artworks := append(current[:index:index], current[index+1:]...)
+	return s.setArtworks(ctx, id, artworks, version)
+}
+
+// ReorderArtworks replaces the order of the exhibition's artworks, artworkIDs
+// must contain exactly the artworks already in the exhibition
+func (s *Store) ReorderArtworks(ctx context.Context, exhibitionID string, artworkIDs []primitive.ObjectID, version int64) (bson.M, error) {
+	id, err := primitive.ObjectIDFromHex(exhibitionID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	current, err := s.findArtworkIDs(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isPermutation(current, artworkIDs) {
+		return nil, &model.ValidationError{Field: "artwork_ids", Message: "must contain exactly the exhibition's artworks"}
+	}
+
+	return s.setArtworks(ctx, id, artworkIDs, version)
+}
+
+// ValidateSnapshot checks a snapshot of an exhibition as AddArtworks checks its
+// artworks, before the exhibition is reverted to it
+func (s *Store) ValidateSnapshot(ctx context.Context, snapshot bson.M) error {
+	raw, err := bson.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	var exhibition struct {
+		ArtworkIDs []primitive.ObjectID `bson:"artwork_ids"`
+	}
+	err = bson.Unmarshal(raw, &exhibition)
+	if err != nil {
+		return fmt.Errorf("error decoding exhibition: %w", err)
+	}
+	if len(exhibition.ArtworkIDs) == 0 {
+		return nil
+	}
+
+	count, err := s.db.Collection("artworks").CountDocuments(ctx, bson.M{"_id": bson.M{"$in": exhibition.ArtworkIDs}, "deleted_at": nil})
+	if err != nil {
+		return err
+	}
+	if count < int64(len(exhibition.ArtworkIDs)) {
+		return &model.ValidationError{Field: "artwork_ids", Message: "contains artworks that do not exist"}
+	}
+	return nil
+}
+
+// SyncArtists sets the exhibition's artists to the artists of its artworks, after
+// it was reverted to a snapshot taken before one of them changed artist
+func (s *Store) SyncArtists(ctx context.Context, exhibitionID string) error {
+	id, err := primitive.ObjectIDFromHex(exhibitionID)
+	if err != nil {
+		return model.ErrInvalidID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": id, "deleted_at": nil}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "artworks",
+			"localField":   "artwork_ids",
+			"foreignField": "_id",
+			"as":           "artworks",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"artwork_ids": 1,
+			"artist_ids":  bson.M{"$setUnion": bson.A{"$artworks.artist_id", bson.A{}}},
+			"current":     bson.M{"$ifNull": bson.A{"$artist_ids", bson.A{}}},
+		}}},
+		{{Key: "$match", Value: bson.M{
+			"$expr": bson.M{"$not": bson.A{bson.M{"$setEquals": bson.A{"$artist_ids", "$current"}}}},
+		}}},
+	}
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	var exhibitions []struct {
+		ArtworkIDs []primitive.ObjectID `bson:"artwork_ids"`
+		ArtistIDs  []primitive.ObjectID `bson:"artist_ids"`
+	}
+	err = cursor.All(ctx, &exhibitions)
+	if err != nil {
+		return fmt.Errorf("error decoding exhibition: %w", err)
+	}
+	if len(exhibitions) == 0 {
+		return nil
+	}
+
+	// the artworks must not have changed since the artists were computed from them
+	filter := bson.M{"_id": id, "artwork_ids": exhibitions[0].ArtworkIDs}
+	update := bson.M{"$set": bson.M{"artist_ids": exhibitions[0].ArtistIDs}, "$inc": bson.M{"version": 1}}
+	_, err = s.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// findArtworkIDs returns the exhibition's artworks if it is still stored at version
+func (s *Store) findArtworkIDs(ctx context.Context, id primitive.ObjectID, version int64) ([]primitive.ObjectID, error) {
+	opts := options.FindOne().SetProjection(bson.M{"artwork_ids": 1, "version": 1})
+	singleRes := s.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": nil}, opts)
+	if err := singleRes.Err(); err == mongo.ErrNoDocuments {
+		return nil, model.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var exhibition struct {
+		ArtworkIDs []primitive.ObjectID `bson:"artwork_ids"`
+		Version    int64                `bson:"version"`
+	}
+	err := singleRes.Decode(&exhibition)
+	if err != nil {
+		err = fmt.Errorf("error decoding exhibition: %w", err)
+		return nil, err
+	}
+	if exhibition.Version != version {
+		return nil, errExhibitionModified
+	}
+
+	return exhibition.ArtworkIDs, nil
+}
+
+// setArtworks stores the exhibition's artworks and keeps its artists in sync with them,
+// the exhibition must not have been modified since it was read at version
+func (s *Store) setArtworks(ctx context.Context, id primitive.ObjectID, artworkIDs []primitive.ObjectID, version int64) (bson.M, error) {
+	if artworkIDs == nil {
+		artworkIDs = []primitive.ObjectID{}
+	}
+
+	artistIDs, err := s.db.Collection("artworks").Distinct(ctx, "artist_id", bson.M{"_id": bson.M{"$in": artworkIDs}})
+	if err != nil {
+		return nil, err
+	}
+	if artistIDs == nil {
+		artistIDs = []interface{}{}
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "artwork_ids", Value: artworkIDs},
+			{Key: "artist_ids", Value: artistIDs},
 		}},
-	}}
+		{Key: "$inc", Value: bson.M{"version": 1}},
+	}
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "deleted_at", Value: nil}, query.VersionFilter(version)}
+	doc, err := util.UpdateAndReturn(ctx, s.collection, filter, update)
+	if err == mongo.ErrNoDocuments {
+		return nil, errExhibitionModified
+	}
+	return doc, err
+}
 
-	lookupPipeline := bson.A{matchArtists}
-	if len(queryParam) > 0 {
-		for _, queryOpts := range queryParam[0].GetPipeline() {
-			lookupPipeline = append(lookupPipeline, queryOpts)
+func indexOf(ids []primitive.ObjectID, id primitive.ObjectID) int {
+	for i, value := range ids {
+		if value == id {
+			return i
 		}
 	}
+	return -1
+}
 
-	lookup := bson.D{{
-		Key: "$lookup",
+// isPermutation reports whether ids holds exactly the values of current in any order
+func isPermutation(current []primitive.ObjectID, ids []primitive.ObjectID) bool {
+	if len(ids) != len(current) {
+		return false
+	}
+	for i, id := range ids {
+		if indexOf(current, id) < 0 || indexOf(ids[:i], id) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// referenceIDs maps an array of IDs into an array of documents holding only the ID
+func referenceIDs(field string) bson.D {
+	return bson.D{{
+		Key: "$map",
 		Value: bson.D{
-			{Key: "from", Value: "artists"},
-			{Key: "let", Value: bson.D{{Key: "artist_ids", Value: "$artist_ids"}}},
-			{Key: "pipeline", Value: lookupPipeline},
-			{Key: "as", Value: "artists"},
+			{Key: "input", Value: bson.D{{Key: "$ifNull", Value: bson.A{field, bson.A{}}}}},
+			{Key: "as", Value: "id"},
+			{Key: "in", Value: bson.D{{Key: "_id", Value: "$$id"}}},
 		},
 	}}
+}
 
-	pipeline := mongo.Pipeline{match, lookup}
-	cursor, err := s.collection.Aggregate(ctx, pipeline)
-	if err != nil {
-		return nil, err
+// decodeExhibitions decodes the exhibitions of the cursor and returns the last
+// document, which positions the cursor of the next page
+func decodeExhibitions(ctx context.Context, cursor *mongo.Cursor) ([]*model.Exhibition, bson.Raw, error) {
+	var last bson.Raw
+	exhibitions := []*model.Exhibition{}
+	for cursor.Next(ctx) {
+		var exhibit model.Exhibition
+		if err := cursor.Decode(&exhibit); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal exhibitions: %w", err)
+		}
+		exhibitions = append(exhibitions, &exhibit)
+		last = cursor.Current
 	}
-	defer cursor.Close(ctx)
 
-	if cursor.RemainingBatchLength() < 1 {
-		return nil, mongo.ErrNoDocuments
+	if err := cursor.Err(); err != nil {
+		return nil, nil, err
 	}
 
-	exhibition := model.Exhibition{}
-	cursor.Next(ctx)
-	cursor.Decode(&exhibition)
-	if err = cursor.Err(); err != nil {
-		return nil, err
+	return exhibitions, last, nil
+}
+
+// matchExhibition narrows the pipeline of a query down to the documents of the
+// exhibition, and adds the stages that follow the match before the query's
+// own. A $text search has to be the first stage so it stays ahead, and the
+// rest of the query's $match runs after the following stages, since a cursor
+// can page on the position they add.
+func matchExhibition(stages []bson.D, byExhibition bson.D, following ...bson.D) mongo.Pipeline {
+	text, rest, ok := splitText(stages[0])
+	if !ok {
+		pipeline := mongo.Pipeline{stages[0], byExhibition}
+		pipeline = append(pipeline, following...)
+		return append(pipeline, stages[1:]...)
 	}
 
-	for _, artist := range exhibition.Artists {
-		model.SortImages(artist.Images)
+	pipeline := mongo.Pipeline{}
+	if len(text) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: text}})
+	}
+	pipeline = append(pipeline, byExhibition)
+	pipeline = append(pipeline, following...)
+	if len(rest) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: rest}})
 	}
+	return append(pipeline, stages[1:]...)
+}
 
-	return exhibition.Artists, nil
+// splitText splits the conditions of a $match stage into its $text search and
+// the rest of them, ok is false when the stage is not a $match
+func splitText(stage bson.D) (text bson.D, rest bson.D, ok bool) {
+	if len(stage) != 1 || stage[0].Key != "$match" {
+		return nil, nil, false
+	}
+	filter, ok := stage[0].Value.(bson.D)
+	if !ok {
+		return nil, nil, false
+	}
+
+	for _, condition := range filter {
+		if condition.Key == "$text" {
+			text = append(text, condition)
+		} else {
+			rest = append(rest, condition)
+		}
+	}
+	return text, rest, true
 }
 
-func (s *Store) InsertMany(ctx context.Context, exhibitions []*model.Exhibition) error {
-	var docs []interface{}
+// artworksLookup joins the artworks of the exhibition into it in their curated
+// order, unless the query sorts them. The artworks carry their position in the
+// curation while they are looked up, so that a query can sort and page on it.
+func artworksLookup(queryParam ...query.QueryParams) bson.D {
+	position := bson.D{{
+		Key: "$addFields",
+		Value: bson.D{{
+			Key: "position",
+			Value: bson.D{{
+				Key:   "$indexOfArray",
+				Value: bson.A{"$$artwork_ids", "$_id"},
+			}},
+		}},
+	}}
+	sortByPosition := bson.D{{Key: "$sort", Value: bson.D{{Key: "position", Value: 1}}}}
 
-	for _, exhibit := range exhibitions {
-		docs = append(docs, exhibit.ConvertToBson())
+	stages := []bson.D{sortByPosition, query.NotDeletedStage}
+	if len(queryParam) > 0 {
+		stages = queryParam[0].GetPipeline()
+	}
+	lookupPipeline := bson.A{matchArtworks, position}
+	for _, stage := range query.JoinArtist(stages) {
+		lookupPipeline = append(lookupPipeline, stage)
 	}
 
-	_, err := s.collection.InsertMany(ctx, docs)
-	return err
+	return bson.D{{
+		Key: "$lookup",
+		Value: bson.D{
+			{Key: "from", Value: "artworks"},
+			{Key: "let", Value: bson.D{{Key: "artwork_ids", Value: "$artwork_ids"}}},
+			{Key: "pipeline", Value: lookupPipeline},
+			{Key: "as", Value: "artworks"},
+		},
+	}}
+}
+
+// artistsLookup joins the artists of the exhibition into it
+func artistsLookup(queryParam ...query.QueryParams) bson.D {
+	lookupPipeline := bson.A{matchArtists}
+	if len(queryParam) > 0 {
+		for _, queryOpts := range queryParam[0].GetPipeline() {
+			lookupPipeline = append(lookupPipeline, queryOpts)
+		}
+	} else {
+		lookupPipeline = append(lookupPipeline, query.NotDeletedStage)
+	}
+
+	return bson.D{{
+		Key: "$lookup",
+		Value: bson.D{
+			{Key: "from", Value: "artists"},
+			{Key: "let", Value: bson.D{{Key: "artist_ids", Value: "$artist_ids"}}},
+			{Key: "pipeline", Value: lookupPipeline},
+			{Key: "as", Value: "artists"},
+		},
+	}}
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/query"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,7 +16,8 @@ import (
 
 var (
 	MongoFailResponse    = bson.D{{Key: "ok", Value: 0}}
-	ErrMongoCommandError = mongo.CommandError{Message: "command failed"}
+	MongoFailRaw, _      = bson.Marshal(MongoFailResponse)
+	ErrMongoCommandError = mongo.CommandError{Message: "command failed", Raw: MongoFailRaw}
 	ErrMongoNoResponses  = mongo.CommandError{Message: "no responses remaining", Labels: []string{"NetworkError"}, Wrapped: errors.New("no responses remaining")}
 
 	artworkID          = "60e0850266d6c13d7b599b69"
@@ -53,6 +55,11 @@ var (
 	}
 )
 
+// writeResponse answers a write that returns the document as it left it stored
+func writeResponse(doc bson.D) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: doc})
+}
+
 func TestFind(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -66,7 +73,7 @@ func TestFind(t *testing.T) {
 			exhibitID:       "invalid_ID",
 			dbResponse:      []bson.D{},
 			expectedExhibit: nil,
-			expectedError:   primitive.ErrInvalidHex,
+			expectedError:   model.ErrInvalidID,
 		},
 		{
 			name:      "no exhibition found",
@@ -75,7 +82,7 @@ func TestFind(t *testing.T) {
 				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch),
 			},
 			expectedExhibit: nil,
-			expectedError:   mongo.ErrNoDocuments,
+			expectedError:   model.ErrNotFound,
 		},
 		{
 			name:      "exhibition found",
@@ -119,6 +126,90 @@ func TestFind(t *testing.T) {
 	}
 }
 
+func TestFindExpanded(t *testing.T) {
+	testCases := []struct {
+		name            string
+		exhibitID       string
+		dbResponse      []bson.D
+		expectedExhibit *model.Exhibition
+		expectedError   error
+	}{
+		{
+			name:            "Invalid exhibitionID",
+			exhibitID:       "invalid_ID",
+			dbResponse:      []bson.D{},
+			expectedExhibit: nil,
+			expectedError:   model.ErrInvalidID,
+		},
+		{
+			name:      "no exhibition found",
+			exhibitID: exhibitID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch),
+			},
+			expectedExhibit: nil,
+			expectedError:   model.ErrNotFound,
+		},
+		{
+			name:      "exhibition found with its relations",
+			exhibitID: exhibitID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, bson.D{
+					{Key: "_id", Value: exhibitObjectID},
+					{Key: "name", Value: "exhibition_name"},
+					{Key: "artworks", Value: bson.A{bson.D{
+						{Key: "_id", Value: artworkObjectID},
+						{Key: "title", Value: "artwork_title"},
+						{Key: "images", Value: imagesBson},
+						{Key: "artist", Value: bson.D{{Key: "_id", Value: artistObjectID}, {Key: "name", Value: "artist_name"}}},
+					}}},
+					{Key: "artists", Value: bson.A{bson.D{
+						{Key: "_id", Value: artistObjectID},
+						{Key: "name", Value: "artist_name"},
+					}}},
+				}),
+			},
+			expectedExhibit: &model.Exhibition{
+				ID:   exhibitObjectID,
+				Name: "exhibition_name",
+				Artworks: []*model.Artwork{{
+					ID:     artworkObjectID,
+					Title:  "artwork_title",
+					Images: images,
+					Artist: &model.Artist{ID: artistObjectID, Name: "artist_name"},
+				}},
+				Artists: []*model.Artist{{ID: artistObjectID, Name: "artist_name"}},
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "aggregate returns error",
+			exhibitID: exhibitID,
+			dbResponse: []bson.D{
+				MongoFailResponse,
+			},
+			expectedExhibit: nil,
+			expectedError:   ErrMongoCommandError,
+		},
+	}
+
+	expand, err := query.NewExhibitionExpand(map[string][]string{"expand": {"artworks,artists"}})
+	require.NoError(t, err)
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			exhibit, err := store.FindExpanded(context.Background(), tc.exhibitID, expand)
+			require.Equal(mt, tc.expectedExhibit, exhibit)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
 func TestFindMany(t *testing.T) {
 	testCases := []struct {
 		name                string
@@ -181,7 +272,7 @@ func TestFindMany(t *testing.T) {
 			mt.AddMockResponses(tc.dbResponse...)
 
 			store := NewStore(mt.DB)
-			exhibitions, err := store.FindMany(context.Background())
+			exhibitions, _, err := store.FindMany(context.Background())
 			require.Equal(mt, tc.expectedExhibitions, exhibitions)
 			require.Equal(mt, tc.expectedError, err)
 		})
@@ -189,6 +280,11 @@ func TestFindMany(t *testing.T) {
 }
 
 func TestFindArtworks(t *testing.T) {
+	exhibitionDoc := bson.D{
+		{Key: "_id", Value: exhibitObjectID},
+		{Key: "artwork_ids", Value: bson.A{artworkObjectID}},
+	}
+
 	testCases := []struct {
 		name             string
 		exhibitionID     string
@@ -201,51 +297,54 @@ func TestFindArtworks(t *testing.T) {
 			exhibitionID:     "invalid_ID",
 			dbResponse:       []bson.D{},
 			expectedArtworks: nil,
-			expectedError:    primitive.ErrInvalidHex,
+			expectedError:    model.ErrInvalidID,
+		},
+		{
+			name:         "exhibit not found",
+			exhibitionID: exhibitID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch),
+			},
+			expectedArtworks: nil,
+			expectedError:    model.ErrNotFound,
 		},
 		{
 			name:         "no exhibit's artwork found",
 			exhibitionID: exhibitID,
 			dbResponse: []bson.D{
-				mtest.CreateCursorResponse(1, "art-house.artworks", mtest.FirstBatch,
-					bson.D{
-						{Key: "_id", Value: exhibitID},
-					},
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: exhibitObjectID}},
 				),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
 			},
-			expectedArtworks: nil,
+			expectedArtworks: []*model.Artwork{},
 			expectedError:    nil,
 		},
 		{
 			name:         "exhibit's artwork found",
 			exhibitionID: exhibitID,
 			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, exhibitionDoc),
 				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch,
 					bson.D{
-						{Key: "_id", Value: exhibitID},
-						{Key: "artworks", Value: bson.A{
-							bson.D{
-								{Key: "_id", Value: artworkID},
-								{Key: "title", Value: "title_1"},
-								{Key: "images", Value: imagesBson},
-								{Key: "year", Value: 1},
-								{Key: "description", Value: "description"},
-								{Key: "artist", Value: bson.D{{
-									Key: "_id", Value: artistID,
-								}}},
-							},
-							bson.D{
-								{Key: "_id", Value: artworkID},
-								{Key: "title", Value: "title_2"},
-								{Key: "images", Value: imagesBson},
-								{Key: "year", Value: 1},
-								{Key: "description", Value: "description"},
-								{Key: "artist", Value: bson.D{{
-									Key: "_id", Value: artistID,
-								}}},
-							},
-						},
-						},
+						{Key: "_id", Value: artworkID},
+						{Key: "title", Value: "title_1"},
+						{Key: "images", Value: imagesBson},
+						{Key: "year", Value: 1},
+						{Key: "description", Value: "description"},
+						{Key: "artist", Value: bson.D{{
+							Key: "_id", Value: artistID,
+						}}},
+					},
+					bson.D{
+						{Key: "_id", Value: artworkID},
+						{Key: "title", Value: "title_2"},
+						{Key: "images", Value: imagesBson},
+						{Key: "year", Value: 1},
+						{Key: "description", Value: "description"},
+						{Key: "artist", Value: bson.D{{
+							Key: "_id", Value: artistID,
+						}}},
 					},
 				),
 			},
@@ -278,6 +377,16 @@ func TestFindArtworks(t *testing.T) {
 			expectedArtworks: nil,
 			expectedError:    ErrMongoCommandError,
 		},
+		{
+			name:         "aggregate returns error",
+			exhibitionID: exhibitID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, exhibitionDoc),
+				MongoFailResponse,
+			},
+			expectedArtworks: nil,
+			expectedError:    ErrMongoCommandError,
+		},
 	}
 
 	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
@@ -287,14 +396,114 @@ func TestFindArtworks(t *testing.T) {
 			mt.AddMockResponses(tc.dbResponse...)
 
 			store := NewStore(mt.DB)
-			artworks, err := store.FindArtworks(context.Background(), tc.exhibitionID)
+			artworks, _, err := store.FindArtworks(context.Background(), tc.exhibitionID)
 			require.Equal(mt, tc.expectedArtworks, artworks)
 			require.Equal(mt, tc.expectedError, err)
 		})
 	}
 }
 
+func TestFindArtworksSearch(t *testing.T) {
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	m.Run("search matches first", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch,
+				bson.D{
+					{Key: "_id", Value: exhibitObjectID},
+					{Key: "artwork_ids", Value: bson.A{artworkObjectID}},
+				},
+			),
+			mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: artworkObjectID}, {Key: "title", Value: "title"}},
+			),
+			mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "total", Value: 1}}),
+		)
+
+		queryParams, err := query.NewArtworkQuery(map[string][]string{"search": {"starry"}})
+		require.NoError(mt, err)
+
+		store := NewStore(mt.DB)
+		artworks, page, err := store.FindArtworks(context.Background(), exhibitID, queryParams)
+		require.NoError(mt, err)
+		require.Len(mt, artworks, 1)
+		require.Equal(mt, int64(1), page.Total)
+
+		for _, event := range mt.GetAllStartedEvents()[1:] {
+			pipeline, err := event.Command.Lookup("pipeline").Array().Values()
+			require.NoError(mt, err)
+			match := pipeline[0].Document().Lookup("$match").Document()
+			_, err = match.LookupErr("$text")
+			require.NoError(mt, err, event.CommandName)
+		}
+	})
+}
+
+func TestFindArtworksNextPage(t *testing.T) {
+	secondID := primitive.NewObjectID()
+	exhibitionDoc := bson.D{
+		{Key: "_id", Value: exhibitObjectID},
+		{Key: "artwork_ids", Value: bson.A{artworkObjectID, secondID}},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	m.Run("cursor pages on the position", func(mt *mtest.T) {
+		store := NewStore(mt.DB)
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, exhibitionDoc),
+			mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: artworkObjectID}, {Key: "position", Value: 0}},
+			),
+			mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "total", Value: 2}}),
+		)
+		first, err := query.NewArtworkQuery(map[string][]string{"limit": {"1"}}, query.CuratedSort)
+		require.NoError(mt, err)
+		_, page, err := store.FindArtworks(context.Background(), exhibitID, first)
+		require.NoError(mt, err)
+		require.NotEmpty(mt, page.NextCursor)
+
+		mt.ClearEvents()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, exhibitionDoc),
+			mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: secondID}, {Key: "position", Value: 1}},
+			),
+			mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "total", Value: 2}}),
+		)
+		second, err := query.NewArtworkQuery(map[string][]string{"limit": {"1"}, "cursor": {page.NextCursor}}, query.CuratedSort)
+		require.NoError(mt, err)
+		artworks, _, err := store.FindArtworks(context.Background(), exhibitID, second)
+		require.NoError(mt, err)
+		require.Len(mt, artworks, 1)
+		require.Equal(mt, secondID, artworks[0].ID)
+
+		// the position is added before the cursor condition on it
+		event := mt.GetAllStartedEvents()[1]
+		stages, err := event.Command.Lookup("pipeline").Array().Values()
+		require.NoError(mt, err)
+		positionAt, cursorAt := -1, -1
+		for i, stage := range stages {
+			doc := stage.Document()
+			if _, err := doc.LookupErr("$addFields", "position"); err == nil {
+				positionAt = i
+			}
+			if _, err := doc.LookupErr("$match", "$or"); err == nil && cursorAt < 0 {
+				cursorAt = i
+			}
+		}
+		require.NotEqual(mt, -1, positionAt)
+		require.Greater(mt, cursorAt, positionAt)
+	})
+}
+
 func TestFindArtists(t *testing.T) {
+	exhibitionDoc := bson.D{
+		{Key: "_id", Value: exhibitObjectID},
+		{Key: "artist_ids", Value: bson.A{artistObjectID}},
+	}
+
 	testCases := []struct {
 		name            string
 		exhibitionID    string
@@ -307,41 +516,44 @@ func TestFindArtists(t *testing.T) {
 			exhibitionID:    "invalid_ID",
 			dbResponse:      []bson.D{},
 			expectedArtists: nil,
-			expectedError:   primitive.ErrInvalidHex,
+			expectedError:   model.ErrInvalidID,
 		},
 		{
-			name:         "no exhibit's artwork found",
+			name:         "exhibit not found",
 			exhibitionID: exhibitID,
 			dbResponse: []bson.D{
-				mtest.CreateCursorResponse(1, "art-house.artworks", mtest.FirstBatch,
-					bson.D{
-						{Key: "_id", Value: exhibitID},
-					},
-				),
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch),
 			},
 			expectedArtists: nil,
+			expectedError:   model.ErrNotFound,
+		},
+		{
+			name:         "no exhibit's artist found",
+			exhibitionID: exhibitID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: exhibitObjectID}},
+				),
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch),
+			},
+			expectedArtists: []*model.Artist{},
 			expectedError:   nil,
 		},
 		{
-			name:         "exhibit's artwork found",
+			name:         "exhibit's artist found",
 			exhibitionID: exhibitID,
 			dbResponse: []bson.D{
-				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch,
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, exhibitionDoc),
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch,
 					bson.D{
-						{Key: "_id", Value: exhibitID},
-						{Key: "artists", Value: bson.A{
-							bson.D{
-								{Key: "_id", Value: artistID},
-								{Key: "name", Value: "name_1"},
-								{Key: "images", Value: imagesBson},
-							},
-							bson.D{
-								{Key: "_id", Value: artistID},
-								{Key: "name", Value: "name_2"},
-								{Key: "images", Value: imagesBson},
-							},
-						},
-						},
+						{Key: "_id", Value: artistID},
+						{Key: "name", Value: "name_1"},
+						{Key: "images", Value: imagesBson},
+					},
+					bson.D{
+						{Key: "_id", Value: artistID},
+						{Key: "name", Value: "name_2"},
+						{Key: "images", Value: imagesBson},
 					},
 				),
 			},
@@ -368,6 +580,16 @@ func TestFindArtists(t *testing.T) {
 			expectedArtists: nil,
 			expectedError:   ErrMongoCommandError,
 		},
+		{
+			name:         "aggregate returns error",
+			exhibitionID: exhibitID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, exhibitionDoc),
+				MongoFailResponse,
+			},
+			expectedArtists: nil,
+			expectedError:   ErrMongoCommandError,
+		},
 	}
 
 	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
@@ -377,7 +599,7 @@ func TestFindArtists(t *testing.T) {
 			mt.AddMockResponses(tc.dbResponse...)
 
 			store := NewStore(mt.DB)
-			artists, err := store.FindArtists(context.Background(), tc.exhibitionID)
+			artists, _, err := store.FindArtists(context.Background(), tc.exhibitionID)
 			require.Equal(mt, tc.expectedArtists, artists)
 			require.Equal(mt, tc.expectedError, err)
 		})
@@ -454,3 +676,469 @@ func TestInsertMany(t *testing.T) {
 		})
 	}
 }
+
+func TestAddArtworks(t *testing.T) {
+	newArtworkObjectID := primitive.NewObjectID()
+	exhibitionResponse := mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, bson.D{
+		{Key: "_id", Value: exhibitObjectID},
+		{Key: "artwork_ids", Value: bson.A{artworkObjectID}},
+		{Key: "version", Value: 2},
+	})
+	staleResponse := mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, bson.D{
+		{Key: "_id", Value: exhibitObjectID},
+		{Key: "artwork_ids", Value: bson.A{artworkObjectID}},
+		{Key: "version", Value: 3},
+	})
+	position := 0
+	outOfRange := 2
+
+	testCases := []struct {
+		name          string
+		exhibitionID  string
+		artworkIDs    []primitive.ObjectID
+		position      *int
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid exhibitID",
+			exhibitionID:  "invalid_ID",
+			artworkIDs:    []primitive.ObjectID{newArtworkObjectID},
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:         "no exhibition found",
+			exhibitionID: exhibitID,
+			artworkIDs:   []primitive.ObjectID{newArtworkObjectID},
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch),
+			},
+			expectedError: model.ErrNotFound,
+		},
+		{
+			name:         "artworks added at position",
+			exhibitionID: exhibitID,
+			artworkIDs:   []primitive.ObjectID{newArtworkObjectID},
+			position:     &position,
+			dbResponse: []bson.D{
+				exhibitionResponse,
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{artistObjectID}}),
+				writeResponse(bson.D{{Key: "_id", Value: exhibitObjectID}, {Key: "version", Value: int64(2)}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:         "position out of range",
+			exhibitionID: exhibitID,
+			artworkIDs:   []primitive.ObjectID{newArtworkObjectID},
+			position:     &outOfRange,
+			dbResponse: []bson.D{
+				exhibitionResponse,
+			},
+			expectedError: &model.ValidationError{Field: "position", Message: "is out of range"},
+		},
+		{
+			name:         "artwork does not exist",
+			exhibitionID: exhibitID,
+			artworkIDs:   []primitive.ObjectID{newArtworkObjectID},
+			dbResponse: []bson.D{
+				exhibitionResponse,
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
+			},
+			expectedError: &model.ValidationError{Field: "artwork_ids", Message: "contains artworks that do not exist"},
+		},
+		{
+			name:         "exhibition stored at another version",
+			exhibitionID: exhibitID,
+			artworkIDs:   []primitive.ObjectID{newArtworkObjectID},
+			dbResponse: []bson.D{
+				staleResponse,
+			},
+			expectedError: &model.PreconditionError{Message: "exhibition has been modified"},
+		},
+		{
+			name:         "exhibition modified while adding",
+			exhibitionID: exhibitID,
+			artworkIDs:   []primitive.ObjectID{newArtworkObjectID},
+			dbResponse: []bson.D{
+				exhibitionResponse,
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{artistObjectID}}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			},
+			expectedError: &model.PreconditionError{Message: "exhibition has been modified"},
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.AddArtworks(context.Background(), tc.exhibitionID, tc.artworkIDs, tc.position, 2)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestRemoveArtwork(t *testing.T) {
+	exhibitionResponse := mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, bson.D{
+		{Key: "_id", Value: exhibitObjectID},
+		{Key: "artwork_ids", Value: bson.A{artworkObjectID}},
+	})
+
+	testCases := []struct {
+		name          string
+		artworkID     string
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid artworkID",
+			artworkID:     "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:      "artwork removed",
+			artworkID: artworkID,
+			dbResponse: []bson.D{
+				exhibitionResponse,
+				mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{}}),
+				writeResponse(bson.D{{Key: "_id", Value: exhibitObjectID}, {Key: "version", Value: int64(2)}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "artwork not in exhibition",
+			artworkID: primitive.NewObjectID().Hex(),
+			dbResponse: []bson.D{
+				exhibitionResponse,
+			},
+			expectedError: model.ErrNotFound,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.RemoveArtwork(context.Background(), exhibitID, tc.artworkID, 0)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestReorderArtworks(t *testing.T) {
+	artworkObjectIDTwo := primitive.NewObjectID()
+	exhibitionResponse := mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, bson.D{
+		{Key: "_id", Value: exhibitObjectID},
+		{Key: "artwork_ids", Value: bson.A{artworkObjectID, artworkObjectIDTwo}},
+	})
+	errNotPermutation := &model.ValidationError{Field: "artwork_ids", Message: "must contain exactly the exhibition's artworks"}
+
+	testCases := []struct {
+		name          string
+		artworkIDs    []primitive.ObjectID
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:       "artworks reordered",
+			artworkIDs: []primitive.ObjectID{artworkObjectIDTwo, artworkObjectID},
+			dbResponse: []bson.D{
+				exhibitionResponse,
+				mtest.CreateSuccessResponse(bson.E{Key: "values", Value: bson.A{artistObjectID}}),
+				writeResponse(bson.D{{Key: "_id", Value: exhibitObjectID}, {Key: "version", Value: int64(2)}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "missing artwork",
+			artworkIDs: []primitive.ObjectID{artworkObjectIDTwo},
+			dbResponse: []bson.D{
+				exhibitionResponse,
+			},
+			expectedError: errNotPermutation,
+		},
+		{
+			name:       "duplicate artwork",
+			artworkIDs: []primitive.ObjectID{artworkObjectID, artworkObjectID},
+			dbResponse: []bson.D{
+				exhibitionResponse,
+			},
+			expectedError: errNotPermutation,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.ReorderArtworks(context.Background(), exhibitID, tc.artworkIDs, 0)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestSyncArtists(t *testing.T) {
+	testCases := []struct {
+		name          string
+		exhibitionID  string
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid exhibitionID",
+			exhibitionID:  "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:         "artists in sync",
+			exhibitionID: exhibitID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch),
+			},
+			expectedError: nil,
+		},
+		{
+			name:         "artists synced",
+			exhibitionID: exhibitID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, bson.D{
+					{Key: "_id", Value: exhibitObjectID},
+					{Key: "artwork_ids", Value: bson.A{artworkObjectID}},
+					{Key: "artist_ids", Value: bson.A{artistObjectID}},
+				}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:         "sync fails with an error",
+			exhibitionID: exhibitID,
+			dbResponse: []bson.D{
+				MongoFailResponse,
+			},
+			expectedError: ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			err := store.SyncArtists(context.Background(), tc.exhibitionID)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestUpsertMany(t *testing.T) {
+	exhibitions := []*model.Exhibition{
+		{Name: "name"},
+	}
+
+	testCases := []struct {
+		name             string
+		exhibitions      []*model.Exhibition
+		dbResponse       []bson.D
+		expectedUpserted int64
+		expectedError    error
+	}{
+		{
+			name:             "nothing to upsert",
+			exhibitions:      []*model.Exhibition{},
+			dbResponse:       []bson.D{},
+			expectedUpserted: 0,
+			expectedError:    nil,
+		},
+		{
+			name:        "upsert exhibitions",
+			exhibitions: exhibitions,
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(
+					bson.E{Key: "n", Value: 1},
+					bson.E{Key: "nModified", Value: 0},
+					bson.E{Key: "upserted", Value: bson.A{
+						bson.D{{Key: "index", Value: 0}, {Key: "_id", Value: primitive.NewObjectID()}},
+					}},
+				),
+			},
+			expectedUpserted: 1,
+			expectedError:    nil,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			res, err := store.UpsertMany(context.Background(), tc.exhibitions)
+			require.Equal(mt, tc.expectedError, err)
+			require.Equal(mt, tc.expectedUpserted, res.UpsertedCount)
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	testCases := []struct {
+		name                string
+		dbResponse          []bson.D
+		expectedExhibitions []*model.Exhibition
+		expectedError       error
+	}{
+		{
+			name: "exhibitions streamed",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch,
+					bson.D{
+						{Key: "_id", Value: exhibitID},
+						{Key: "name", Value: "exhibit_name"},
+						{Key: "artists", Value: bson.A{bson.D{{Key: "_id", Value: artistObjectID}}}},
+						{Key: "artworks", Value: bson.A{bson.D{{Key: "_id", Value: artworkObjectID}}}},
+					},
+				),
+			},
+			expectedExhibitions: []*model.Exhibition{
+				{
+					ID:       exhibitObjectID,
+					Name:     "exhibit_name",
+					Artists:  []*model.Artist{{ID: artistObjectID}},
+					Artworks: []*model.Artwork{{ID: artworkObjectID}},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "stream returns error",
+			dbResponse: []bson.D{
+				MongoFailResponse,
+			},
+			expectedExhibitions: nil,
+			expectedError:       ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			var exhibitions []*model.Exhibition
+			store := NewStore(mt.DB)
+			err := store.Stream(context.Background(), func(exhibition *model.Exhibition) error {
+				exhibitions = append(exhibitions, exhibition)
+				return nil
+			})
+			require.Equal(mt, tc.expectedExhibitions, exhibitions)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	testCases := []struct {
+		name          string
+		exhibitionID  string
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid exhibitID",
+			exhibitionID:  "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:         "exhibition deleted",
+			exhibitionID: exhibitID,
+			dbResponse: []bson.D{
+				writeResponse(bson.D{{Key: "_id", Value: exhibitObjectID}, {Key: "version", Value: int64(2)}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:         "no exhibition found",
+			exhibitionID: exhibitID,
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			},
+			expectedError: model.ErrNotFound,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.Delete(context.Background(), tc.exhibitionID)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	testCases := []struct {
+		name          string
+		exhibitionID  string
+		dbResponse    []bson.D
+		expectedError error
+	}{
+		{
+			name:          "invalid exhibitID",
+			exhibitionID:  "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:         "exhibition restored",
+			exhibitionID: exhibitID,
+			dbResponse: []bson.D{
+				writeResponse(bson.D{{Key: "_id", Value: exhibitObjectID}, {Key: "version", Value: int64(2)}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:         "exhibition not in trash",
+			exhibitionID: exhibitID,
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			},
+			expectedError: model.ErrNotFound,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.Restore(context.Background(), tc.exhibitionID)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
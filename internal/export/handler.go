@@ -0,0 +1,104 @@
+package export
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/iamnotrodger/art-house-api/internal/artist"
+	"github.com/iamnotrodger/art-house-api/internal/artwork"
+	"github.com/iamnotrodger/art-house-api/internal/exhibition"
+	"github.com/iamnotrodger/art-house-api/internal/middleware"
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/util"
+)
+
+type Handler struct {
+	artworkStore    *artwork.Store
+	artistStore     *artist.Store
+	exhibitionStore *exhibition.Store
+}
+
+func NewHandler(artworkStore *artwork.Store, artistStore *artist.Store, exhibitionStore *exhibition.Store) *Handler {
+	return &Handler{
+		artworkStore:    artworkStore,
+		artistStore:     artistStore,
+		exhibitionStore: exhibitionStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	// an export streams for as long as there are records, past the server's write timeout
+	exports := router.PathPrefix("/api/export").Subrouter()
+	exports.Use(middleware.NoWriteTimeout)
+	exports.HandleFunc("/artworks", h.ExportArtworks).Methods("GET")
+	exports.HandleFunc("/artists", h.ExportArtists).Methods("GET")
+	exports.HandleFunc("/exhibitions", h.ExportExhibitions).Methods("GET")
+}
+
+func (h *Handler) ExportArtworks(w http.ResponseWriter, r *http.Request) {
+	format, err := GetFormat(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writer := NewWriter(w, format, "artworks", ArtworkHeader)
+	count := 0
+	err = h.artworkStore.Stream(r.Context(), func(artwork *model.Artwork) error {
+		count++
+		return writer.Write(artwork, ArtworkRecord(artwork))
+	})
+	finish(w, writer, count, err)
+}
+
+func (h *Handler) ExportArtists(w http.ResponseWriter, r *http.Request) {
+	format, err := GetFormat(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writer := NewWriter(w, format, "artists", ArtistHeader)
+	count := 0
+	err = h.artistStore.Stream(r.Context(), func(artist *model.Artist) error {
+		count++
+		return writer.Write(artist, ArtistRecord(artist))
+	})
+	finish(w, writer, count, err)
+}
+
+func (h *Handler) ExportExhibitions(w http.ResponseWriter, r *http.Request) {
+	format, err := GetFormat(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writer := NewWriter(w, format, "exhibitions", ExhibitionHeader)
+	count := 0
+	err = h.exhibitionStore.Stream(r.Context(), func(exhibition *model.Exhibition) error {
+		count++
+		return writer.Write(exhibition, ExhibitionRecord(exhibition))
+	})
+	finish(w, writer, count, err)
+}
+
+// finish flushes the export, an error can only be reported to the client
+// before the first record has been written. After it the response is aborted,
+// so that the client sees a truncated export rather than a complete one.
+func finish(w http.ResponseWriter, writer Writer, count int, err error) {
+	if err != nil && count == 0 {
+		w.Header().Del("Content-Disposition")
+		util.HandleError(w, err)
+		return
+	} else if err != nil {
+		log.Println(err)
+		panic(http.ErrAbortHandler)
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,44 @@
+package export
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinish(t *testing.T) {
+	errStream := errors.New("cursor failed")
+
+	t.Run("error before the first record", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writer := NewWriter(w, FormatNDJSON, "artists", ArtistHeader)
+
+		finish(w, writer, 0, errStream)
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("error after the first record aborts the response", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writer := NewWriter(w, FormatNDJSON, "artists", ArtistHeader)
+		require.NoError(t, writer.Write(&model.Artist{Name: "name"}, nil))
+
+		require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			finish(w, writer, 1, errStream)
+		})
+	})
+
+	t.Run("export completed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		writer := NewWriter(w, FormatNDJSON, "artists", ArtistHeader)
+		require.NoError(t, writer.Write(&model.Artist{Name: "name"}, nil))
+
+		finish(w, writer, 1, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `{"_id":"000000000000000000000000","name":"name"}`+"\n", w.Body.String())
+	})
+}
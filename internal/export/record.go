@@ -0,0 +1,73 @@
+package export
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+)
+
+var (
+	ArtworkHeader    = []string{"_id", "title", "year", "description", "artist_id", "artist_name", "image_url"}
+	ArtistHeader     = []string{"_id", "name", "image_url"}
+	ExhibitionHeader = []string{"_id", "name", "artist_ids", "artwork_ids", "image_url"}
+)
+
+func ArtworkRecord(artwork *model.Artwork) []string {
+	artistID, artistName := "", ""
+	if artwork.Artist != nil {
+		artistID = artwork.Artist.ID.Hex()
+		artistName = artwork.Artist.Name
+	}
+
+	year := ""
+	if artwork.Year != 0 {
+		year = strconv.Itoa(artwork.Year)
+	}
+
+	return []string{
+		artwork.ID.Hex(),
+		artwork.Title,
+		year,
+		artwork.Description,
+		artistID,
+		artistName,
+		largestImageURL(artwork.Images),
+	}
+}
+
+func ArtistRecord(artist *model.Artist) []string {
+	return []string{
+		artist.ID.Hex(),
+		artist.Name,
+		largestImageURL(artist.Images),
+	}
+}
+
+func ExhibitionRecord(exhibition *model.Exhibition) []string {
+	artistIDs := []string{}
+	for _, artist := range exhibition.Artists {
+		artistIDs = append(artistIDs, artist.ID.Hex())
+	}
+
+	artworkIDs := []string{}
+	for _, artwork := range exhibition.Artworks {
+		artworkIDs = append(artworkIDs, artwork.ID.Hex())
+	}
+
+	return []string{
+		exhibition.ID.Hex(),
+		exhibition.Name,
+		strings.Join(artistIDs, " "),
+		strings.Join(artworkIDs, " "),
+		largestImageURL(exhibition.Images),
+	}
+}
+
+// largestImageURL returns the URL of the last image, images are sorted by size in ascending order
+func largestImageURL(images []*model.Image) string {
+	if len(images) == 0 {
+		return ""
+	}
+	return images[len(images)-1].Url
+}
@@ -0,0 +1,129 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+
+	// flushInterval is the number of records written between flushes to the client
+	flushInterval = 100
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// Writer writes exported records to the response as they are read
+type Writer interface {
+	// Write writes a single record, v is written as NDJSON and record as a CSV row
+	Write(v interface{}, record []string) error
+	Flush() error
+}
+
+// GetFormat returns the export format requested by the format parameter, or by the Accept header
+func GetFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if format != FormatNDJSON && format != FormatCSV {
+			return "", ErrUnsupportedFormat
+		}
+		return format, nil
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		return FormatCSV, nil
+	}
+	return FormatNDJSON, nil
+}
+
+// NewWriter sets the response headers for the format and returns a writer for the records
+func NewWriter(w http.ResponseWriter, format string, name string, header []string) Writer {
+	if format == FormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+name+".csv\"")
+		return &csvWriter{response: w, writer: csv.NewWriter(w), header: header}
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+name+".ndjson\"")
+	return &ndjsonWriter{response: w, encoder: json.NewEncoder(w)}
+}
+
+type ndjsonWriter struct {
+	response http.ResponseWriter
+	encoder  *json.Encoder
+	count    int
+}
+
+func (n *ndjsonWriter) Write(v interface{}, record []string) error {
+	err := n.encoder.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	n.count++
+	if n.count%flushInterval == 0 {
+		return n.Flush()
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) Flush() error {
+	if flusher, ok := n.response.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+type csvWriter struct {
+	response      http.ResponseWriter
+	writer        *csv.Writer
+	header        []string
+	headerWritten bool
+	count         int
+}
+
+func (c *csvWriter) Write(v interface{}, record []string) error {
+	err := c.writeHeader()
+	if err != nil {
+		return err
+	}
+	err = c.writer.Write(record)
+	if err != nil {
+		return err
+	}
+
+	c.count++
+	if c.count%flushInterval == 0 {
+		return c.Flush()
+	}
+	return nil
+}
+
+func (c *csvWriter) Flush() error {
+	err := c.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	c.writer.Flush()
+	if err = c.writer.Error(); err != nil {
+		return err
+	}
+	if flusher, ok := c.response.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.writer.Write(c.header)
+}
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGetFormat(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		accept         string
+		expectedFormat string
+		expectedError  error
+	}{
+		{
+			name:           "default format",
+			target:         "/api/export/artworks",
+			expectedFormat: FormatNDJSON,
+		},
+		{
+			name:           "format from accept header",
+			target:         "/api/export/artworks",
+			accept:         "text/csv",
+			expectedFormat: FormatCSV,
+		},
+		{
+			name:           "format parameter overrides accept header",
+			target:         "/api/export/artworks?format=ndjson",
+			accept:         "text/csv",
+			expectedFormat: FormatNDJSON,
+		},
+		{
+			name:          "unsupported format",
+			target:        "/api/export/artworks?format=xml",
+			expectedError: ErrUnsupportedFormat,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", test.target, nil)
+			r.Header.Set("Accept", test.accept)
+
+			format, err := GetFormat(r)
+			require.Equal(t, test.expectedFormat, format)
+			require.Equal(t, test.expectedError, err)
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	artist := &model.Artist{ID: primitive.NewObjectID(), Name: "name, with comma"}
+
+	tests := []struct {
+		name                string
+		format              string
+		artists             []*model.Artist
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "ndjson",
+			format:              FormatNDJSON,
+			artists:             []*model.Artist{artist, artist},
+			expectedContentType: "application/x-ndjson",
+			expectedBody: `{"_id":"` + artist.ID.Hex() + `","name":"name, with comma"}` + "\n" +
+				`{"_id":"` + artist.ID.Hex() + `","name":"name, with comma"}` + "\n",
+		},
+		{
+			name:                "csv",
+			format:              FormatCSV,
+			artists:             []*model.Artist{artist},
+			expectedContentType: "text/csv",
+			expectedBody:        "_id,name,image_url\n" + artist.ID.Hex() + ",\"name, with comma\",\n",
+		},
+		{
+			name:                "empty csv",
+			format:              FormatCSV,
+			artists:             []*model.Artist{},
+			expectedContentType: "text/csv",
+			expectedBody:        "_id,name,image_url\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			writer := NewWriter(w, test.format, "artists", ArtistHeader)
+			for _, artist := range test.artists {
+				require.NoError(t, writer.Write(artist, ArtistRecord(artist)))
+			}
+			require.NoError(t, writer.Flush())
+
+			require.Equal(t, test.expectedContentType, w.Header().Get("Content-Type"))
+			require.Equal(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
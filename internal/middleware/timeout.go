@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+type connKey struct{}
+
+// ConnContext keeps the connection of a request in its context, so that a
+// handler can change its deadlines. It is set as the server's ConnContext.
+func ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+// NoWriteTimeout clears the server's write deadline for handlers that stream
+// their response for longer than the server's write timeout. The server sets
+// the deadline again for the next request on the connection.
+func NoWriteTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, ok := r.Context().Value(connKey{}).(net.Conn); ok {
+			err := conn.SetWriteDeadline(time.Time{})
+			if err != nil {
+				log.Println(err)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
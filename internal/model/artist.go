@@ -1,9 +1,43 @@
 package model
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Artist struct {
-	ID     primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	Name   string             `json:"name,omitempty" bson:"name,omitempty"`
-	Images []*Image           `json:"images,omitempty" bson:"images,omitempty"`
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name      string             `json:"name,omitempty" bson:"name,omitempty"`
+	Images    []*Image           `json:"images,omitempty" bson:"images,omitempty"`
+	Version   int64              `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+}
+
+func (a *Artist) ConvertToBson() bson.D {
+	var doc bson.D
+
+	if !a.ID.IsZero() {
+		doc = append(doc, bson.E{Key: "_id", Value: a.ID})
+	}
+
+	doc = append(doc,
+		bson.E{Key: "name", Value: a.Name},
+		bson.E{Key: "images", Value: a.Images},
+	)
+
+	if a.Version != 0 {
+		doc = append(doc, bson.E{Key: "version", Value: a.Version})
+	}
+
+	return doc
+}
+
+func (a *Artist) Validate() error {
+	if strings.TrimSpace(a.Name) == "" {
+		return &ValidationError{Field: "name", Message: "is required"}
+	}
+	return nil
 }
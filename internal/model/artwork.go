@@ -1,17 +1,34 @@
 package model
 
 import (
+	"strings"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ArtworkYearMin is the earliest year an artwork can be dated to
+const ArtworkYearMin = -50000
+
 type Artwork struct {
 	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	Title       string             `json:"title,omitempty" bson:"title,omitempty"`
 	Images      []*Image           `json:"images,omitempty" bson:"images,omitempty"`
-	Year        int                `json:"year,omitempty" bson:"year, omitempty"`
+	Year        int                `json:"year,omitempty" bson:"year,omitempty"`
 	Description string             `json:"description,omitempty" bson:"description,omitempty"`
 	Artist      *Artist            `json:"artist,omitempty" bson:"artist,omitempty"`
+	Version     int64              `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt   *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+}
+
+// ArtworkPatch holds the fields of a partial artwork update, nil fields are left unchanged
+type ArtworkPatch struct {
+	Title       *string  `json:"title"`
+	Images      []*Image `json:"images"`
+	Year        *int     `json:"year"`
+	Description *string  `json:"description"`
+	Artist      *Artist  `json:"artist"`
 }
 
 func (a *Artwork) ConvertToBson() bson.D {
@@ -24,10 +41,95 @@ func (a *Artwork) ConvertToBson() bson.D {
 	doc = append(doc,
 		bson.E{Key: "title", Value: a.Title},
 		bson.E{Key: "images", Value: a.Images},
-		bson.E{Key: "year", Value: a.Year},
+	)
+	// an undated artwork is stored without a year
+	if a.Year != 0 {
+		doc = append(doc, bson.E{Key: "year", Value: a.Year})
+	}
+	doc = append(doc,
 		bson.E{Key: "description", Value: a.Description},
 		bson.E{Key: "artist_id", Value: a.Artist.ID},
 	)
 
+	if a.Version != 0 {
+		doc = append(doc, bson.E{Key: "version", Value: a.Version})
+	}
+
+	return doc
+}
+
+func (a *Artwork) Validate() error {
+	if err := validateArtworkTitle(a.Title); err != nil {
+		return err
+	}
+	if err := validateArtworkYear(a.Year); err != nil {
+		return err
+	}
+	return validateArtworkArtist(a.Artist)
+}
+
+// ConvertToBson returns the fields to $set on the stored artwork
+func (p *ArtworkPatch) ConvertToBson() bson.D {
+	doc := bson.D{}
+
+	if p.Title != nil {
+		doc = append(doc, bson.E{Key: "title", Value: *p.Title})
+	}
+	if p.Images != nil {
+		doc = append(doc, bson.E{Key: "images", Value: p.Images})
+	}
+	if p.Year != nil {
+		doc = append(doc, bson.E{Key: "year", Value: *p.Year})
+	}
+	if p.Description != nil {
+		doc = append(doc, bson.E{Key: "description", Value: *p.Description})
+	}
+	if p.Artist != nil {
+		doc = append(doc, bson.E{Key: "artist_id", Value: p.Artist.ID})
+	}
+
 	return doc
 }
+
+func (p *ArtworkPatch) Validate() error {
+	if p.Title != nil {
+		if err := validateArtworkTitle(*p.Title); err != nil {
+			return err
+		}
+	}
+	if p.Year != nil {
+		if err := validateArtworkYear(*p.Year); err != nil {
+			return err
+		}
+	}
+	if p.Artist != nil {
+		if err := validateArtworkArtist(p.Artist); err != nil {
+			return err
+		}
+	}
+	if len(p.ConvertToBson()) == 0 {
+		return &ValidationError{Field: "body", Message: "has no fields to update"}
+	}
+	return nil
+}
+
+func validateArtworkTitle(title string) error {
+	if strings.TrimSpace(title) == "" {
+		return &ValidationError{Field: "title", Message: "is required"}
+	}
+	return nil
+}
+
+func validateArtworkYear(year int) error {
+	if year < ArtworkYearMin || year > time.Now().Year() {
+		return &ValidationError{Field: "year", Message: "is out of range"}
+	}
+	return nil
+}
+
+func validateArtworkArtist(artist *Artist) error {
+	if artist == nil || artist.ID.IsZero() {
+		return &ValidationError{Field: "artist", Message: "is required"}
+	}
+	return nil
+}
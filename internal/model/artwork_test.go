@@ -0,0 +1,100 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestArtworkValidate(t *testing.T) {
+	artist := &Artist{ID: primitive.NewObjectID()}
+
+	tests := []struct {
+		name          string
+		artwork       *Artwork
+		expectedError error
+	}{
+		{
+			name:          "valid artwork",
+			artwork:       &Artwork{Title: "title", Year: 1503, Artist: artist},
+			expectedError: nil,
+		},
+		{
+			name:          "missing title",
+			artwork:       &Artwork{Title: " ", Artist: artist},
+			expectedError: &ValidationError{Field: "title", Message: "is required"},
+		},
+		{
+			name:          "year in the future",
+			artwork:       &Artwork{Title: "title", Year: 100000, Artist: artist},
+			expectedError: &ValidationError{Field: "year", Message: "is out of range"},
+		},
+		{
+			name:          "missing artist",
+			artwork:       &Artwork{Title: "title"},
+			expectedError: &ValidationError{Field: "artist", Message: "is required"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expectedError, test.artwork.Validate())
+		})
+	}
+}
+
+func TestArtworkConvertToBson(t *testing.T) {
+	artist := &Artist{ID: primitive.NewObjectID()}
+
+	dated := &Artwork{Title: "title", Year: 1503, Artist: artist}
+	require.Equal(t, bson.D{
+		{Key: "title", Value: "title"},
+		{Key: "images", Value: []*Image(nil)},
+		{Key: "year", Value: 1503},
+		{Key: "description", Value: ""},
+		{Key: "artist_id", Value: artist.ID},
+	}, dated.ConvertToBson())
+
+	undated := &Artwork{Title: "title", Artist: artist}
+	require.Equal(t, bson.D{
+		{Key: "title", Value: "title"},
+		{Key: "images", Value: []*Image(nil)},
+		{Key: "description", Value: ""},
+		{Key: "artist_id", Value: artist.ID},
+	}, undated.ConvertToBson())
+}
+
+func TestArtworkPatchValidate(t *testing.T) {
+	title := "title"
+	emptyTitle := ""
+
+	tests := []struct {
+		name          string
+		patch         *ArtworkPatch
+		expectedError error
+	}{
+		{
+			name:          "valid patch",
+			patch:         &ArtworkPatch{Title: &title},
+			expectedError: nil,
+		},
+		{
+			name:          "empty title",
+			patch:         &ArtworkPatch{Title: &emptyTitle},
+			expectedError: &ValidationError{Field: "title", Message: "is required"},
+		},
+		{
+			name:          "empty patch",
+			patch:         &ArtworkPatch{},
+			expectedError: &ValidationError{Field: "body", Message: "has no fields to update"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expectedError, test.patch.Validate())
+		})
+	}
+}
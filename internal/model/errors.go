@@ -0,0 +1,77 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNotFound reports a document that does not exist, the stores translate
+	// the errors of the driver into it
+	ErrNotFound = errors.New("document not found")
+	// ErrInvalidID reports an ID that is not a hex ObjectID
+	ErrInvalidID = errors.New("invalid ID")
+	// ErrUnavailable reports a dependency of the API that cannot be reached
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// ValidationError reports a field that failed validation
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// ConflictError reports a write that conflicts with the stored state of a document
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// PreconditionError reports a write made against a version of a document that is no longer stored
+type PreconditionError struct {
+	Message string
+}
+
+func (e *PreconditionError) Error() string {
+	return e.Message
+}
+
+// The reasons a query parameter is rejected for when parameters are checked strictly
+const (
+	ReasonUnknown    = "unknown"
+	ReasonInvalid    = "invalid"
+	ReasonOutOfRange = "out_of_range"
+)
+
+// ParameterError reports a query parameter that cannot be used
+type ParameterError struct {
+	Parameter string `json:"parameter"`
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (e *ParameterError) Error() string {
+	return fmt.Sprintf("%s %s", e.Parameter, e.Message)
+}
+
+// ParametersError reports every query parameter of a request that strict
+// checking rejects
+type ParametersError struct {
+	Errors []*ParameterError `json:"errors"`
+}
+
+func (e *ParametersError) Error() string {
+	messages := []string{}
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
@@ -1,22 +1,27 @@
 package model
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Exhibition struct {
-	ID       primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	Name     string             `json:"name,omitempty" bson:"name,omitempty"`
-	Images   []*Image           `json:"images,omitempty" bson:"images,omitempty"`
-	Artists  []*Artist          `json:"artists,omitempty" bson:"artists,omitempty"`
-	Artworks []*Artwork         `json:"artworks,omitempty" bson:"artworks,omitempty"`
+	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name        string             `json:"name,omitempty" bson:"name,omitempty"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	Images      []*Image           `json:"images,omitempty" bson:"images,omitempty"`
+	Artists     []*Artist          `json:"artists,omitempty" bson:"artists,omitempty"`
+	Artworks    []*Artwork         `json:"artworks,omitempty" bson:"artworks,omitempty"`
+	Version     int64              `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt   *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
 }
 
 func (e *Exhibition) ConvertToBson() bson.D {
 	var doc bson.D
-	var artists []primitive.ObjectID
-	var artworks []primitive.ObjectID
+	artists := []primitive.ObjectID{}
+	artworks := []primitive.ObjectID{}
 
 	for _, artist := range e.Artists {
 		artists = append(artists, artist.ID)
@@ -32,10 +37,15 @@ func (e *Exhibition) ConvertToBson() bson.D {
 
 	doc = append(doc,
 		bson.E{Key: "name", Value: e.Name},
+		bson.E{Key: "description", Value: e.Description},
 		bson.E{Key: "images", Value: e.Images},
 		bson.E{Key: "artist_ids", Value: artists},
 		bson.E{Key: "artwork_ids", Value: artworks},
 	)
 
+	if e.Version != 0 {
+		doc = append(doc, bson.E{Key: "version", Value: e.Version})
+	}
+
 	return doc
 }
@@ -0,0 +1,12 @@
+package model
+
+// FacetBucket counts the documents of a list that share a value
+type FacetBucket struct {
+	// Value is the decade, or the ID of the artist or exhibition, of the bucket
+	Value interface{} `json:"value" bson:"_id"`
+	Name  string      `json:"name,omitempty" bson:"name,omitempty"`
+	Count int64       `json:"count" bson:"count"`
+}
+
+// Facets holds the buckets of each facet of a list by the facet's name
+type Facets map[string][]*FacetBucket
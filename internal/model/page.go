@@ -0,0 +1,11 @@
+package model
+
+// Page describes where a page of a list sits in the full list
+type Page struct {
+	// Total is the number of documents in the full list
+	Total int64
+	Limit int64
+	Skip  int64
+	// NextCursor positions the page after this one, it is empty on the last page
+	NextCursor string
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+	RevisionPurge   = "purge"
+	RevisionRevert  = "revert"
+)
+
+// Revision is a snapshot of a stored document taken after a write
+type Revision struct {
+	ID         primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Collection string             `json:"collection" bson:"collection"`
+	DocumentID primitive.ObjectID `json:"document_id" bson:"document_id"`
+	Number     int64              `json:"number" bson:"number"`
+	Action     string             `json:"action" bson:"action"`
+	Author     string             `json:"author,omitempty" bson:"author,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	Snapshot   bson.M             `json:"snapshot,omitempty" bson:"snapshot,omitempty"`
+}
+
+// RevisionChange is a field that differs between two revisions
+type RevisionChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type RevisionDiff struct {
+	From    int64             `json:"from"`
+	To      int64             `json:"to"`
+	Changes []*RevisionChange `json:"changes"`
+}
+
+// DiffRevisions compares the top level fields of the snapshots of two revisions
+func DiffRevisions(from *Revision, to *Revision) *RevisionDiff {
+	fields := map[string]bool{}
+	for field := range from.Snapshot {
+		fields[field] = true
+	}
+	for field := range to.Snapshot {
+		fields[field] = true
+	}
+
+	names := []string{}
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	changes := []*RevisionChange{}
+	for _, field := range names {
+		fromValue, toValue := from.Snapshot[field], to.Snapshot[field]
+		if !reflect.DeepEqual(fromValue, toValue) {
+			changes = append(changes, &RevisionChange{Field: field, From: fromValue, To: toValue})
+		}
+	}
+
+	return &RevisionDiff{
+		From:    from.Number,
+		To:      to.Number,
+		Changes: changes,
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	from := &Revision{
+		Number:   1,
+		Snapshot: bson.M{"title": "before", "year": int32(1503), "description": "description"},
+	}
+	to := &Revision{
+		Number:   3,
+		Snapshot: bson.M{"title": "after", "year": int32(1503), "images": bson.A{"url"}},
+	}
+
+	expected := &RevisionDiff{
+		From: 1,
+		To:   3,
+		Changes: []*RevisionChange{
+			{Field: "description", From: "description", To: nil},
+			{Field: "images", From: nil, To: bson.A{"url"}},
+			{Field: "title", From: "before", To: "after"},
+		},
+	}
+	require.Equal(t, expected, DiffRevisions(from, to))
+}
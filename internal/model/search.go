@@ -0,0 +1,24 @@
+package model
+
+// The types of documents a search finds
+const (
+	SearchArtwork    = "artwork"
+	SearchArtist     = "artist"
+	SearchExhibition = "exhibition"
+)
+
+// SearchResult is a document found by a search, only the field of its type is set
+type SearchResult struct {
+	Type       string      `json:"type"`
+	Score      float64     `json:"score"`
+	Artwork    *Artwork    `json:"artwork,omitempty"`
+	Artist     *Artist     `json:"artist,omitempty"`
+	Exhibition *Exhibition `json:"exhibition,omitempty"`
+}
+
+// Search is a page of the results of a search ranked by relevance, along with
+// how many documents of each type the search found
+type Search struct {
+	Results []*SearchResult  `json:"results"`
+	Counts  map[string]int64 `json:"counts"`
+}
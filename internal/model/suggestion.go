@@ -0,0 +1,11 @@
+package model
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Suggestion is an artwork title, artist name or exhibition name that
+// completes what is typed in a search
+type Suggestion struct {
+	Type string             `json:"type"`
+	ID   primitive.ObjectID `json:"_id"`
+	Text string             `json:"text"`
+}
@@ -1,79 +1,141 @@
 package query
 
 import (
-	"strconv"
-
 	"github.com/iamnotrodger/art-house-api/cmd/config"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var artistSortable = []string{"_id", "name", "deleted_at"}
+
 type ArtistQueryParams struct {
 	limit int64
 	skip  int64
-	sort  map[string]int
+	checking
+	sorting
+	projecting
+	filtering
+	search string
 }
 
-func NewArtistQuery(parameters map[string][]string) *ArtistQueryParams {
+// NewArtistQuery parses the query parameters of a list, defaultSort orders the
+// list when the parameters do not sort it
+func NewArtistQuery(parameters map[string][]string, defaultSort ...bson.E) (*ArtistQueryParams, error) {
+	params := NewParameters(parameters)
 	query := &ArtistQueryParams{}
-
-	if limit, ok := parameters["limit"]; ok {
-		query.setLimitFromString(limit[0])
-	} else {
-		query.limit = config.Global.ArtistLimit
+	query.params = params
+	query.defaultSort = defaultSort
+	query.sortable = artistSortable
+	query.selectable = artistSelectable
+	query.filterable = artistFilterable
+
+	query.limit = params.limit("limit", artistLimits())
+	query.skip = params.skip()
+	// envelope is read by the response of the page, it is only checked here
+	params.flag("envelope")
+	query.SetSearch(params.Get("search"))
+	query.searching = query.isSearchValid()
+	if params.Has("sort") {
+		if err := query.SetSort(params.Values("sort")); err != nil {
+			return nil, err
+		}
 	}
-	if skip, ok := parameters["skip"]; ok {
-		query.setSkipFromString(skip[0])
+	if params.Has("cursor") {
+		if err := query.SetCursor(params.Get("cursor")); err != nil {
+			return nil, err
+		}
 	}
-	if sort, ok := parameters["sort"]; ok {
-		query.SetSort(sort)
+	if params.Has("fields") {
+		if err := query.SetFields(params.Values("fields")); err != nil {
+			return nil, err
+		}
+	}
+	if params.Has("filter") {
+		if err := query.SetFilter(params.Get("filter")); err != nil {
+			return nil, err
+		}
 	}
 
-	return query
+	return query, nil
 }
 
 func (q *ArtistQueryParams) GetFilter() bson.D {
-	return bson.D{}
+	filter := q.GetCountFilter()
+	filter = append(filter, q.GetCursorFilter()...)
+	return filter
+}
+
+// GetCountFilter matches the whole list, regardless of the page
+func (q *ArtistQueryParams) GetCountFilter() bson.D {
+	filter := bson.D{}
+	filter = append(filter, NotDeletedFilter...)
+	if q.isSearchValid() {
+		filter = append(filter, TextFilter(q.search))
+	}
+	filter = append(filter, q.getExpressionFilter()...)
+	return filter
+}
+
+// GetCountPipeline counts the whole list, regardless of the page
+func (q *ArtistQueryParams) GetCountPipeline() []bson.D {
+	return CountPipeline(q.GetCountFilter())
 }
 
 func (q *ArtistQueryParams) GetFindOptions() *options.FindOptions {
 	options := options.Find()
-	if q.isSortValid() {
-		sort := getSortAsBson(q.sort)
-		options.SetSort(sort)
-	}
+	options.SetSort(q.GetSort())
 	if q.isSkipValid() {
 		options.SetSkip(q.skip)
 	}
 	options.SetLimit(q.limit)
+	if projection := q.GetProjection(); len(projection) > 0 {
+		options.SetProjection(projection)
+	}
 	return options
 }
 
 func (q *ArtistQueryParams) GetPipeline() []bson.D {
 	pipeline := []bson.D{}
 
-	if q.isSortValid() {
-		sort := bson.D{{Key: "$sort", Value: q.sort}}
-		pipeline = append(pipeline, sort)
-	}
+	match := bson.D{{Key: "$match", Value: q.GetFilter()}}
+	pipeline = append(pipeline, match)
+
+	sort := bson.D{{Key: "$sort", Value: q.GetSort()}}
+	pipeline = append(pipeline, sort)
 	if q.isSkipValid() {
 		skip := bson.D{{Key: "$skip", Value: q.skip}}
 		pipeline = append(pipeline, skip)
 	}
 	limit := bson.D{{Key: "$limit", Value: q.limit}}
 	pipeline = append(pipeline, limit)
+	if projection := q.GetProjection(); len(projection) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: projection}})
+	}
 
 	return pipeline
 }
 
-func (q *ArtistQueryParams) SetLimit(limit int64) {
-	if limit < config.Global.ArtistLimitMin {
-		q.limit = config.Global.ArtistLimit
-	} else if limit > config.Global.ArtistLimitMax {
-		q.limit = config.Global.ArtworkLimitMax
-	} else {
-		q.limit = limit
+// GetProjection returns the projection of the selected fields, it is empty
+// when every field is returned
+func (q *ArtistQueryParams) GetProjection() bson.D {
+	return q.projection(q.GetSort())
+}
+
+func (q *ArtistQueryParams) GetLimit() int64 {
+	return q.limit
+}
+
+// GetSkip returns the number of documents skipped, which is none when a cursor
+// positions the list
+func (q *ArtistQueryParams) GetSkip() int64 {
+	if !q.isSkipValid() {
+		return 0
 	}
+	return q.skip
+}
+
+func (q *ArtistQueryParams) SetLimit(limit int64) {
+	q.limit = artistLimits().clamp(limit)
 }
 func (q *ArtistQueryParams) SetSkip(skip int64) {
 	if skip > 0 {
@@ -81,36 +143,25 @@ func (q *ArtistQueryParams) SetSkip(skip int64) {
 	}
 }
 
-func (q *ArtistQueryParams) SetSort(sortArray []string) {
-	q.sort = map[string]int{}
-	for _, sortString := range sortArray {
-		key, value := parseSort(sortString)
-		if key != "" {
-			q.sort[key] = value
-		}
-	}
-}
-
-func (q *ArtistQueryParams) setLimitFromString(limitString string) {
-	limit, err := strconv.ParseInt(limitString, 0, 64)
-	if err != nil {
-		q.limit = config.Global.ArtworkLimit
-	} else {
-		q.SetLimit(limit)
+func (q *ArtistQueryParams) SetSearch(search string) {
+	if search != "" {
+		q.search = search
 	}
 }
 
-func (q *ArtistQueryParams) setSkipFromString(skipString string) {
-	skip, err := strconv.ParseInt(skipString, 0, 64)
-	if err == nil {
-		q.SetSkip(skip)
-	}
+// isSkipValid reports whether to skip, a cursor positions the list by itself
+func (q *ArtistQueryParams) isSkipValid() bool {
+	return q.skip > 0 && !q.HasCursor()
 }
 
-func (q *ArtistQueryParams) isSortValid() bool {
-	return q.sort != nil && len(q.sort) > 0
+func (q *ArtistQueryParams) isSearchValid() bool {
+	return q.search != ""
 }
 
-func (q *ArtistQueryParams) isSkipValid() bool {
-	return q.skip > 0
+func artistLimits() limits {
+	return limits{
+		value: config.Global.ArtistLimit,
+		min:   config.Global.ArtistLimitMin,
+		max:   config.Global.ArtistLimitMax,
+	}
 }
@@ -1,95 +1,167 @@
 package query
 
 import (
-	"strconv"
+	"strings"
 
 	"github.com/iamnotrodger/art-house-api/cmd/config"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var artworkSortable = []string{"_id", "title", "year", "deleted_at", "artist.name"}
+
 type ArtworkQueryParams struct {
-	limit  int64
-	skip   int64
-	sort   map[string]int
+	limit int64
+	skip  int64
+	checking
+	sorting
+	projecting
+	filtering
 	search string
+	filter artworkFilter
+	facets []string
 }
 
-func NewArtworkQuery(parameters map[string][]string) *ArtworkQueryParams {
+// NewArtworkQuery parses the query parameters of a list, defaultSort orders the
+// list when the parameters do not sort it
+func NewArtworkQuery(parameters map[string][]string, defaultSort ...bson.E) (*ArtworkQueryParams, error) {
+	params := NewParameters(parameters)
 	query := &ArtworkQueryParams{}
-
-	if limit, ok := parameters["limit"]; ok {
-		query.setLimitFromString(limit[0])
-	} else {
-		query.limit = config.Global.ArtworkLimit
+	query.params = params
+	query.defaultSort = defaultSort
+	query.sortable = artworkSortable
+	query.selectable = artworkSelectable
+	query.filterable = artworkFilterable
+
+	query.limit = params.limit("limit", artworkLimits())
+	query.skip = params.skip()
+	// envelope is read by the response of the page, it is only checked here
+	params.flag("envelope")
+	query.SetSearch(params.Get("search"))
+	query.searching = query.isSearchValid()
+	if params.Has("sort") {
+		if err := query.SetSort(params.Values("sort")); err != nil {
+			return nil, err
+		}
 	}
-	if skip, ok := parameters["skip"]; ok {
-		query.setSkipFromString(skip[0])
+	if params.Has("cursor") {
+		if err := query.SetCursor(params.Get("cursor")); err != nil {
+			return nil, err
+		}
 	}
-	if sort, ok := parameters["sort"]; ok {
-		query.SetSort(sort)
+	if params.Has("fields") {
+		if err := query.SetFields(params.Values("fields")); err != nil {
+			return nil, err
+		}
+	}
+	if params.Has("filter") {
+		if err := query.SetFilter(params.Get("filter")); err != nil {
+			return nil, err
+		}
 	}
-	if search, ok := parameters["search"]; ok {
-		query.SetSearch(search[0])
+	if err := query.filter.parse(params); err != nil {
+		return nil, err
+	}
+	if err := query.setFacets(params); err != nil {
+		return nil, err
 	}
 
-	return query
+	return query, nil
 }
 
+// GetFilter matches the artworks of the page on their own fields. The
+// conditions on the joined artist, including a cursor that sorts on the
+// artist, need a join and are only applied by GetPipeline.
 func (q *ArtworkQueryParams) GetFilter() bson.D {
+	filter := q.GetCountFilter()
+	if !q.joinsArtist() {
+		filter = append(filter, q.GetCursorFilter()...)
+	}
+	return filter
+}
+
+// GetCountFilter matches the whole list, regardless of the page
+func (q *ArtworkQueryParams) GetCountFilter() bson.D {
 	filter := bson.D{}
+	filter = append(filter, NotDeletedFilter...)
 	if q.isSearchValid() {
-		search := bson.D{{Key: "$search", Value: q.search}}
-		text := bson.D{{Key: "$text", Value: search}}
-		filter = append(filter, text...)
+		filter = append(filter, TextFilter(q.search))
+	}
+	filter = append(filter, q.filter.getFilter()...)
+	if !q.filtersOn("artist.") {
+		filter = append(filter, q.getExpressionFilter()...)
 	}
 	return filter
 }
 
+// GetCountPipeline counts the whole list, regardless of the page
+func (q *ArtworkQueryParams) GetCountPipeline() []bson.D {
+	return append(q.getListStages(), CountStage)
+}
+
 func (q *ArtworkQueryParams) GetFindOptions() *options.FindOptions {
 	options := options.Find()
-	if q.isSortValid() {
-		sort := getSortAsBson(q.sort)
-		options.SetSort(sort)
-	}
+	options.SetSort(q.GetSort())
 	if q.isSkipValid() {
 		options.SetSkip(q.skip)
 	}
 	options.SetLimit(q.limit)
+	if projection := q.GetProjection(); len(projection) > 0 {
+		options.SetProjection(projection)
+	}
 	return options
 }
 
 func (q *ArtworkQueryParams) GetPipeline() []bson.D {
 	pipeline := []bson.D{}
 
-	if q.isSearchValid() {
-		search := bson.D{{Key: "$search", Value: q.search}}
-		text := bson.D{{Key: "$text", Value: search}}
-		match := bson.D{{Key: "$match", Value: text}}
-		pipeline = append(pipeline, match)
-	}
-	if q.isSortValid() {
-		sort := bson.D{{Key: "$sort", Value: q.sort}}
-		pipeline = append(pipeline, sort)
+	match := bson.D{{Key: "$match", Value: q.GetFilter()}}
+	pipeline = append(pipeline, match)
+	if q.joinsArtist() {
+		pipeline = append(pipeline, ArtworkLookupStage, ArtworkUnwindStage)
+		artistFilter := append(q.getJoinedFilter(), q.GetCursorFilter()...)
+		if len(artistFilter) > 0 {
+			pipeline = append(pipeline, bson.D{{Key: "$match", Value: artistFilter}})
+		}
 	}
+	sort := bson.D{{Key: "$sort", Value: q.GetSort()}}
+	pipeline = append(pipeline, sort)
 	if q.isSkipValid() {
 		skip := bson.D{{Key: "$skip", Value: q.skip}}
 		pipeline = append(pipeline, skip)
 	}
 	limit := bson.D{{Key: "$limit", Value: q.limit}}
 	pipeline = append(pipeline, limit)
+	if projection := q.GetProjection(); len(projection) > 0 {
+		// the artist is joined before the projection, which only keeps it when it is selected
+		pipeline = JoinArtist(pipeline)
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: projection}})
+	}
 
 	return pipeline
 }
 
-func (q *ArtworkQueryParams) SetLimit(limit int64) {
-	if limit < config.Global.ArtworkLimitMin {
-		q.limit = config.Global.ArtworkLimit
-	} else if limit > config.Global.ArtworkLimitMax {
-		q.limit = config.Global.ArtworkLimitMax
-	} else {
-		q.limit = limit
+// GetProjection returns the projection of the selected fields, it is empty
+// when every field is returned
+func (q *ArtworkQueryParams) GetProjection() bson.D {
+	return q.projection(q.GetSort())
+}
+
+func (q *ArtworkQueryParams) GetLimit() int64 {
+	return q.limit
+}
+
+// GetSkip returns the number of documents skipped, which is none when a cursor
+// positions the list
+func (q *ArtworkQueryParams) GetSkip() int64 {
+	if !q.isSkipValid() {
+		return 0
 	}
+	return q.skip
+}
+
+func (q *ArtworkQueryParams) SetLimit(limit int64) {
+	q.limit = artworkLimits().clamp(limit)
 }
 func (q *ArtworkQueryParams) SetSkip(skip int64) {
 	if skip > 0 {
@@ -97,46 +169,60 @@ func (q *ArtworkQueryParams) SetSkip(skip int64) {
 	}
 }
 
-func (q *ArtworkQueryParams) SetSort(sortArray []string) {
-	q.sort = map[string]int{}
-	for _, sortString := range sortArray {
-		key, value := parseSort(sortString)
-		if key != "" {
-			q.sort[key] = value
-		}
-	}
-}
-
 func (q *ArtworkQueryParams) SetSearch(search string) {
 	if search != "" {
 		q.search = search
 	}
 }
 
-func (q *ArtworkQueryParams) setLimitFromString(limitString string) {
-	limit, err := strconv.ParseInt(limitString, 0, 64)
-	if err != nil {
-		q.limit = config.Global.ArtworkLimit
-	} else {
-		q.SetLimit(limit)
-	}
+// isSkipValid reports whether to skip, a cursor positions the list by itself
+func (q *ArtworkQueryParams) isSkipValid() bool {
+	return q.skip > 0 && !q.HasCursor()
+}
+
+func (q *ArtworkQueryParams) isSearchValid() bool {
+	return q.search != ""
 }
 
-func (q *ArtworkQueryParams) setSkipFromString(skipString string) {
-	skip, err := strconv.ParseInt(skipString, 0, 64)
-	if err == nil {
-		q.SetSkip(skip)
+// getListStages returns the stages that narrow the artworks down to the whole
+// list, regardless of the page
+func (q *ArtworkQueryParams) getListStages() []bson.D {
+	pipeline := []bson.D{{{Key: "$match", Value: q.GetCountFilter()}}}
+	if artistFilter := q.getJoinedFilter(); len(artistFilter) > 0 {
+		pipeline = append(pipeline, ArtworkLookupStage, ArtworkUnwindStage)
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: artistFilter}})
 	}
+	return pipeline
 }
 
-func (q *ArtworkQueryParams) isSortValid() bool {
-	return q.sort != nil && len(q.sort) > 0
+// joinsArtist reports whether the pipeline needs the artist before it sorts
+// and pages, otherwise only the artworks of the page are joined with JoinArtist
+func (q *ArtworkQueryParams) joinsArtist() bool {
+	if len(q.getJoinedFilter()) > 0 {
+		return true
+	}
+	for _, field := range q.GetSort() {
+		if strings.HasPrefix(field.Key, "artist.") {
+			return true
+		}
+	}
+	return false
 }
 
-func (q *ArtworkQueryParams) isSkipValid() bool {
-	return q.skip > 0
+// getJoinedFilter returns the conditions on the joined artist. A filter
+// expression on the artist is applied as a whole once the artist is joined.
+func (q *ArtworkQueryParams) getJoinedFilter() bson.D {
+	filter := q.filter.getArtistFilter()
+	if q.filtersOn("artist.") {
+		filter = append(filter, q.getExpressionFilter()...)
+	}
+	return filter
 }
 
-func (q *ArtworkQueryParams) isSearchValid() bool {
-	return q.search != ""
+func artworkLimits() limits {
+	return limits{
+		value: config.Global.ArtworkLimit,
+		min:   config.Global.ArtworkLimitMin,
+		max:   config.Global.ArtworkLimitMax,
+	}
 }
@@ -0,0 +1,106 @@
+package query
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// facetLimit caps the buckets of the facets that count by artist or exhibition,
+// the buckets with the most artworks are kept
+const facetLimit = 50
+
+// artworkFacets are the facets a list of artworks can be counted by, in the
+// order the stages of each facet are built
+var artworkFacets = []string{"decade", "artist", "exhibition"}
+
+// setFacets reads the facets from facets=decade,artist or from repeated
+// parameters, they are kept in the order of artworkFacets
+func (q *ArtworkQueryParams) setFacets(params *Parameters) error {
+	facets, err := params.Choices("facets", artworkFacets)
+	if err != nil {
+		return err
+	}
+	q.facets = facets
+	return nil
+}
+
+// HasFacets reports whether the list is counted by facets along with its page
+func (q *ArtworkQueryParams) HasFacets() bool {
+	return len(q.facets) > 0
+}
+
+// GetFacetPipeline counts the whole list, regardless of the page, into the
+// buckets of each facet. It filters the artworks with the same stages as
+// GetPipeline and returns a single document of model.Facets.
+func (q *ArtworkQueryParams) GetFacetPipeline() []bson.D {
+	facets := bson.D{}
+	for _, facet := range q.facets {
+		var stages bson.A
+		switch facet {
+		case "decade":
+			stages = decadeFacet()
+		case "artist":
+			stages = artistFacet()
+		case "exhibition":
+			stages = exhibitionFacet()
+		}
+		facets = append(facets, bson.E{Key: facet, Value: stages})
+	}
+
+	pipeline := q.getListStages()
+	return append(pipeline, bson.D{{Key: "$facet", Value: facets}})
+}
+
+// decadeFacet counts the dated artworks by the decade they were made in, there
+// is no year 0 and artworks stored with it are undated
+func decadeFacet() bson.A {
+	dated := bson.D{{Key: "$match", Value: bson.D{{Key: "year", Value: bson.D{{Key: "$nin", Value: bson.A{nil, 0}}}}}}}
+	decade := bson.D{{Key: "$multiply", Value: bson.A{
+		bson.D{{Key: "$floor", Value: bson.D{{Key: "$divide", Value: bson.A{"$year", 10}}}}},
+		10,
+	}}}
+	group := bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: bson.D{{Key: "$toInt", Value: decade}}},
+		{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+	}}}
+	sort := bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}}
+	return bson.A{dated, group, sort}
+}
+
+// artistFacet counts the artworks by their artist
+func artistFacet() bson.A {
+	group := bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: "$artist_id"},
+		{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+	}}}
+	lookup := bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: "artists"},
+		{Key: "localField", Value: "_id"},
+		{Key: "foreignField", Value: "_id"},
+		{Key: "as", Value: "artist"},
+	}}}
+	unwind := bson.D{{Key: "$unwind", Value: "$artist"}}
+	project := bson.D{{Key: "$project", Value: bson.D{
+		{Key: "name", Value: "$artist.name"},
+		{Key: "count", Value: 1},
+	}}}
+	return bson.A{group, byCount(), limitBuckets(), lookup, unwind, project}
+}
+
+// exhibitionFacet counts the artworks by the exhibitions they are shown in, an
+// artwork is counted once in each of its exhibitions
+func exhibitionFacet() bson.A {
+	unwind := bson.D{{Key: "$unwind", Value: "$exhibitions"}}
+	notDeleted := bson.D{{Key: "$match", Value: bson.D{{Key: "exhibitions.deleted_at", Value: nil}}}}
+	group := bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: "$exhibitions._id"},
+		{Key: "name", Value: bson.D{{Key: "$first", Value: "$exhibitions.name"}}},
+		{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+	}}}
+	return bson.A{exhibitionLookupStage, unwind, notDeleted, group, byCount(), limitBuckets()}
+}
+
+func byCount() bson.D {
+	return bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}}
+}
+
+func limitBuckets() bson.D {
+	return bson.D{{Key: "$limit", Value: facetLimit}}
+}
@@ -0,0 +1,160 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// exhibitionLookupStage joins the exhibitions that show each artwork into it
+var exhibitionLookupStage = bson.D{{
+	Key: "$lookup",
+	Value: bson.D{
+		{Key: "from", Value: "exhibitions"},
+		{Key: "localField", Value: "_id"},
+		{Key: "foreignField", Value: "artwork_ids"},
+		{Key: "as", Value: "exhibitions"},
+	},
+}}
+
+// artworkFilter narrows a list of artworks down by their fields
+type artworkFilter struct {
+	yearGte      *int64
+	yearLte      *int64
+	artistIDs    []primitive.ObjectID
+	exhibitionID primitive.ObjectID
+	// exhibitionArtworks are the artworks of the exhibition, which exhibitions
+	// reference, read by FindExhibitionArtworks. They are nil until they are read.
+	exhibitionArtworks []primitive.ObjectID
+	hasImages          *bool
+	titlePrefix        string
+	// artistNamePrefix is a condition on the joined artist
+	artistNamePrefix string
+}
+
+func (f *artworkFilter) parse(params *Parameters) error {
+	var err error
+	if f.yearGte, err = parseYear(params, "year_gte"); err != nil {
+		return err
+	}
+	if f.yearLte, err = parseYear(params, "year_lte"); err != nil {
+		return err
+	}
+	if f.artistIDs, err = params.IDs("artist_id"); err != nil {
+		return err
+	}
+	if f.exhibitionID, err = params.ID("exhibition_id"); err != nil {
+		return err
+	}
+	if f.hasImages, err = params.Bool("has_images"); err != nil {
+		return err
+	}
+	f.titlePrefix = params.Get("title_prefix")
+	f.artistNamePrefix = params.Get("artist_name_prefix")
+
+	return nil
+}
+
+// getFilter returns the conditions on the fields of the artworks themselves
+func (f *artworkFilter) getFilter() bson.D {
+	filter := bson.D{}
+	if f.yearGte != nil || f.yearLte != nil {
+		year := bson.D{}
+		if f.yearGte != nil {
+			year = append(year, bson.E{Key: "$gte", Value: *f.yearGte})
+		}
+		if f.yearLte != nil {
+			year = append(year, bson.E{Key: "$lte", Value: *f.yearLte})
+		}
+		filter = append(filter, bson.E{Key: "year", Value: year})
+	}
+	if len(f.artistIDs) > 0 {
+		filter = append(filter, bson.E{Key: "artist_id", Value: bson.D{{Key: "$in", Value: f.artistIDs}}})
+	}
+	if !f.exhibitionID.IsZero() {
+		// the filter would silently match no artworks without them
+		if f.exhibitionArtworks == nil {
+			panic(errExhibitionArtworksUnread)
+		}
+		filter = append(filter, bson.E{Key: "_id", Value: bson.D{{Key: "$in", Value: f.exhibitionArtworks}}})
+	}
+	if f.hasImages != nil {
+		filter = append(filter, bson.E{Key: "images.0", Value: bson.D{{Key: "$exists", Value: *f.hasImages}}})
+	}
+	if f.titlePrefix != "" {
+		filter = append(filter, bson.E{Key: "title", Value: prefixRegex(f.titlePrefix)})
+	}
+	return filter
+}
+
+// getArtistFilter returns the conditions on the joined artist
+func (f *artworkFilter) getArtistFilter() bson.D {
+	filter := bson.D{}
+	if f.artistNamePrefix != "" {
+		filter = append(filter, bson.E{Key: "artist.name", Value: prefixRegex(f.artistNamePrefix)})
+	}
+	return filter
+}
+
+// errExhibitionArtworksUnread is the panic of a query filtered by exhibition_id
+// that is built before FindExhibitionArtworks read the exhibition's artworks
+var errExhibitionArtworksUnread = errors.New("query: the artworks of exhibition_id must be read with FindExhibitionArtworks before the query is built")
+
+// FindExhibitionArtworks reads the artworks of the exhibition that a query of
+// artworks filters on into the query, once, so that the list matches them by
+// their IDs rather than joining every artwork with the exhibitions. It must be
+// called before the filter or pipelines of such a query are built, and does
+// nothing for other queries.
+func FindExhibitionArtworks(ctx context.Context, db *mongo.Database, queryParam ...QueryParams) error {
+	if len(queryParam) == 0 {
+		return nil
+	}
+	q, ok := queryParam[0].(*ArtworkQueryParams)
+	if !ok || q.filter.exhibitionID.IsZero() {
+		return nil
+	}
+
+	var exhibition struct {
+		ArtworkIDs []primitive.ObjectID `bson:"artwork_ids"`
+	}
+	opts := options.FindOne().SetProjection(bson.D{{Key: "artwork_ids", Value: 1}})
+	filter := bson.D{{Key: "_id", Value: q.filter.exhibitionID}, {Key: "deleted_at", Value: nil}}
+	singleRes := db.Collection("exhibitions").FindOne(ctx, filter, opts)
+	if err := singleRes.Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			q.filter.exhibitionArtworks = []primitive.ObjectID{}
+			return nil
+		}
+		return err
+	}
+	if err := singleRes.Decode(&exhibition); err != nil {
+		return fmt.Errorf("error decoding exhibition: %w", err)
+	}
+
+	q.filter.exhibitionArtworks = exhibition.ArtworkIDs
+	if q.filter.exhibitionArtworks == nil {
+		q.filter.exhibitionArtworks = []primitive.ObjectID{}
+	}
+	return nil
+}
+
+func prefixRegex(prefix string) primitive.Regex {
+	return primitive.Regex{Pattern: "^" + regexp.QuoteMeta(prefix), Options: "i"}
+}
+
+func parseYear(params *Parameters, parameter string) (*int64, error) {
+	year, ok, err := params.Int(parameter)
+	if err != nil {
+		return nil, &model.ParameterError{Parameter: parameter, Message: "must be a year"}
+	} else if !ok {
+		return nil, nil
+	}
+	return &year, nil
+}
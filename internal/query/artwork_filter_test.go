@@ -0,0 +1,211 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestArtworkFilter(t *testing.T) {
+	artistOne := primitive.NewObjectID()
+	artistTwo := primitive.NewObjectID()
+	exhibitionID := primitive.NewObjectID()
+
+	query, err := NewArtworkQuery(map[string][]string{
+		"year_gte":      {"1880"},
+		"year_lte":      {"1890"},
+		"artist_id":     {artistOne.Hex(), artistTwo.Hex()},
+		"exhibition_id": {exhibitionID.Hex()},
+		"has_images":    {"true"},
+		"title_prefix":  {"Star."},
+	})
+	require.NoError(t, err)
+
+	artworks := []primitive.ObjectID{primitive.NewObjectID()}
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	m.Run("exhibition artworks", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: exhibitionID},
+			{Key: "artwork_ids", Value: artworks},
+		}))
+		require.NoError(mt, FindExhibitionArtworks(context.Background(), mt.DB, query))
+	})
+
+	filter := bson.D{
+		{Key: "deleted_at", Value: nil},
+		{Key: "year", Value: bson.D{{Key: "$gte", Value: int64(1880)}, {Key: "$lte", Value: int64(1890)}}},
+		{Key: "artist_id", Value: bson.D{{Key: "$in", Value: []primitive.ObjectID{artistOne, artistTwo}}}},
+		{Key: "_id", Value: bson.D{{Key: "$in", Value: artworks}}},
+		{Key: "images.0", Value: bson.D{{Key: "$exists", Value: true}}},
+		{Key: "title", Value: primitive.Regex{Pattern: `^Star\.`, Options: "i"}},
+	}
+	require.Equal(t, filter, query.GetFilter())
+
+	pipeline := query.GetPipeline()
+	require.Equal(t, bson.D{{Key: "$match", Value: filter}}, pipeline[0])
+	require.Equal(t, "$sort", pipeline[1][0].Key)
+
+	countPipeline := query.GetCountPipeline()
+	require.Equal(t, pipeline[:1], countPipeline[:1])
+	require.Equal(t, CountStage, countPipeline[1])
+}
+
+func TestArtworkFilterMissingExhibition(t *testing.T) {
+	exhibitionID := primitive.NewObjectID()
+	query, err := NewArtworkQuery(map[string][]string{"exhibition_id": {exhibitionID.Hex()}})
+	require.NoError(t, err)
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	m.Run("no exhibition found", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch))
+		require.NoError(mt, FindExhibitionArtworks(context.Background(), mt.DB, query))
+	})
+
+	require.Equal(t, bson.D{
+		{Key: "deleted_at", Value: nil},
+		{Key: "_id", Value: bson.D{{Key: "$in", Value: []primitive.ObjectID{}}}},
+	}, query.GetFilter())
+}
+
+func TestArtworkFilterUnreadExhibition(t *testing.T) {
+	exhibitionID := primitive.NewObjectID()
+	query, err := NewArtworkQuery(map[string][]string{"exhibition_id": {exhibitionID.Hex()}})
+	require.NoError(t, err)
+
+	require.PanicsWithValue(t, errExhibitionArtworksUnread, func() { query.GetFilter() })
+	require.PanicsWithValue(t, errExhibitionArtworksUnread, func() { query.GetCountPipeline() })
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	m.Run("exhibition without artworks", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: exhibitionID},
+		}))
+		require.NoError(mt, FindExhibitionArtworks(context.Background(), mt.DB, query))
+	})
+
+	require.Equal(t, bson.D{
+		{Key: "deleted_at", Value: nil},
+		{Key: "_id", Value: bson.D{{Key: "$in", Value: []primitive.ObjectID{}}}},
+	}, query.GetFilter())
+}
+
+func TestArtworkFilterErrors(t *testing.T) {
+	testCases := []struct {
+		parameters    map[string][]string
+		expectedError error
+	}{
+		{
+			parameters:    map[string][]string{"year_gte": {"1880s"}},
+			expectedError: &model.ParameterError{Parameter: "year_gte", Message: "must be a year"},
+		},
+		{
+			parameters:    map[string][]string{"year_lte": {""}},
+			expectedError: &model.ParameterError{Parameter: "year_lte", Message: "must be a year"},
+		},
+		{
+			parameters:    map[string][]string{"artist_id": {primitive.NewObjectID().Hex(), "artist"}},
+			expectedError: &model.ParameterError{Parameter: "artist_id", Message: "must be an ID"},
+		},
+		{
+			parameters:    map[string][]string{"exhibition_id": {"exhibition"}},
+			expectedError: &model.ParameterError{Parameter: "exhibition_id", Message: "must be an ID"},
+		},
+		{
+			parameters:    map[string][]string{"has_images": {"some"}},
+			expectedError: &model.ParameterError{Parameter: "has_images", Message: "must be true or false"},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewArtworkQuery(tc.parameters)
+		require.Equal(t, tc.expectedError, err)
+	}
+}
+
+func TestArtworkArtistPipeline(t *testing.T) {
+	query, err := NewArtworkQuery(map[string][]string{"year_gte": {"1880"}})
+	require.NoError(t, err)
+
+	pipeline := query.GetPipeline()
+	require.False(t, JoinsArtist(pipeline))
+	joined := JoinArtist(pipeline)
+	require.Equal(t, []bson.D{ArtworkLookupStage, ArtworkUnwindStage}, joined[len(joined)-2:])
+
+	id := primitive.NewObjectID()
+	last, _ := bson.Marshal(bson.D{{Key: "_id", Value: id}, {Key: "artist", Value: bson.D{{Key: "name", Value: "Monet"}}}})
+	cursor, err := encodeCursor(bson.D{{Key: "artist.name", Value: 1}, {Key: "_id", Value: 1}}, last)
+	require.NoError(t, err)
+
+	query, err = NewArtworkQuery(map[string][]string{
+		"year_gte":           {"1880"},
+		"artist_name_prefix": {"mo"},
+		"sort":               {"artist.name:asc"},
+		"cursor":             {cursor},
+	})
+	require.NoError(t, err)
+
+	pipeline = query.GetPipeline()
+	require.Equal(t, bson.D{{Key: "$match", Value: bson.D{
+		{Key: "deleted_at", Value: nil},
+		{Key: "year", Value: bson.D{{Key: "$gte", Value: int64(1880)}}},
+	}}}, pipeline[0])
+	require.Equal(t, ArtworkLookupStage, pipeline[1])
+	require.Equal(t, ArtworkUnwindStage, pipeline[2])
+	require.Equal(t, bson.D{{Key: "$match", Value: bson.D{
+		{Key: "artist.name", Value: primitive.Regex{Pattern: "^mo", Options: "i"}},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "artist.name", Value: bson.D{{Key: "$gt", Value: "Monet"}}}},
+			bson.D{{Key: "artist.name", Value: "Monet"}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+		}},
+	}}}, pipeline[3])
+	require.Equal(t, bson.D{{Key: "$sort", Value: bson.D{{Key: "artist.name", Value: 1}, {Key: "_id", Value: 1}}}}, pipeline[4])
+	require.Equal(t, pipeline, JoinArtist(pipeline))
+
+	countPipeline := query.GetCountPipeline()
+	require.Equal(t, pipeline[:3], countPipeline[:3])
+	require.Equal(t, bson.D{{Key: "$match", Value: bson.D{
+		{Key: "artist.name", Value: primitive.Regex{Pattern: "^mo", Options: "i"}},
+	}}}, countPipeline[3])
+	require.Equal(t, CountStage, countPipeline[4])
+}
+
+func TestArtworkFacets(t *testing.T) {
+	query, err := NewArtworkQuery(map[string][]string{
+		"artist_name_prefix": {"mo"},
+		"facets":             {"exhibition,decade", "artist"},
+		"sort":               {"year:desc"},
+		"limit":              {"1"},
+	})
+	require.NoError(t, err)
+	require.True(t, query.HasFacets())
+
+	countPipeline := query.GetCountPipeline()
+	facetPipeline := query.GetFacetPipeline()
+	require.Equal(t, countPipeline[:len(countPipeline)-1], facetPipeline[:len(facetPipeline)-1])
+
+	facets := facetPipeline[len(facetPipeline)-1][0].Value.(bson.D)
+	require.Equal(t, "decade", facets[0].Key)
+	require.Equal(t, "artist", facets[1].Key)
+	require.Equal(t, "exhibition", facets[2].Key)
+
+	// undated artworks are stored without a year or with year 0, and are not counted in a decade
+	decade := facets[0].Value.(bson.A)
+	require.Equal(t, bson.D{{Key: "$match", Value: bson.D{
+		{Key: "year", Value: bson.D{{Key: "$nin", Value: bson.A{nil, 0}}}},
+	}}}, decade[0])
+
+	query, err = NewArtworkQuery(map[string][]string{})
+	require.NoError(t, err)
+	require.False(t, query.HasFacets())
+
+	_, err = NewArtworkQuery(map[string][]string{"facets": {"decade,year"}})
+	require.Equal(t, &model.ParameterError{Parameter: "facets", Message: "must be one of decade, artist, exhibition"}, err)
+}
@@ -0,0 +1,246 @@
+package query
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errInvalidSort    = &model.ParameterError{Parameter: "sort", Message: "must be field:asc or field:desc"}
+	errInvalidCursor  = &model.ParameterError{Parameter: "cursor", Message: "is invalid"}
+	errCursorMismatch = &model.ParameterError{Parameter: "cursor", Message: "does not match the sort"}
+	errCursorScore    = &model.ParameterError{Parameter: "cursor", Message: "cannot page a sort on score, use skip"}
+)
+
+// cursorToken is the content of a cursor, the sort keys of the last document of
+// a page and their values
+type cursorToken struct {
+	Keys   []string      `bson:"k"`
+	Values []interface{} `bson:"v"`
+}
+
+// sorting orders a list and positions it after the cursor of a previous page.
+// Ties are broken on _id so that every document has a stable position, which
+// is what lets a cursor resume a list where its page ended.
+type sorting struct {
+	sort        bson.D
+	defaultSort bson.D
+	after       []interface{}
+	// sortable lists the fields the list can be sorted on, the fields that are
+	// indexed or cheap enough to sort on
+	sortable []string
+	// searching allows sorting on the relevance score of a $text search
+	searching bool
+}
+
+// GetSort returns the sort of the list, ending with _id
+func (s *sorting) GetSort() bson.D {
+	sort := bson.D{}
+	if len(s.sort) > 0 {
+		sort = append(sort, s.sort...)
+	} else {
+		sort = append(sort, s.defaultSort...)
+	}
+
+	for _, field := range sort {
+		if field.Key == "_id" {
+			return sort
+		}
+	}
+	return append(sort, bson.E{Key: "_id", Value: 1})
+}
+
+// GetCursorFilter matches the documents that come after the cursor, it is
+// empty when there is no cursor
+func (s *sorting) GetCursorFilter() bson.D {
+	if !s.HasCursor() {
+		return bson.D{}
+	}
+
+	sort := s.GetSort()
+	branches := bson.A{}
+	for i, field := range sort {
+		branch := bson.D{}
+		for j := 0; j < i; j++ {
+			branch = append(branch, bson.E{Key: sort[j].Key, Value: s.after[j]})
+		}
+
+		after, ok := afterValue(field, s.after[i])
+		if !ok {
+			continue
+		}
+		branches = append(branches, append(branch, after))
+	}
+
+	return bson.D{{Key: "$or", Value: branches}}
+}
+
+// SetSort orders the list by the sort parameters in the order they are given,
+// each one a sortable field and asc or desc
+func (s *sorting) SetSort(sortArray []string) error {
+	sort := bson.D{}
+	for _, sortString := range sortArray {
+		key, value, err := parseSort(sortString)
+		if err != nil {
+			return err
+		}
+		if key == ScoreKey {
+			if err = s.checkScoreSort(value); err != nil {
+				return err
+			}
+			sort = append(sort, ScoreSort)
+			continue
+		}
+		if !s.isSortable(key) {
+			message := fmt.Sprintf("cannot sort on %s, sortable fields are %s", key, strings.Join(s.sortable, ", "))
+			return &model.ParameterError{Parameter: "sort", Message: message}
+		}
+		if hasKey(sort, key) {
+			return &model.ParameterError{Parameter: "sort", Message: fmt.Sprintf("sorts on %s more than once", key)}
+		}
+		sort = append(sort, bson.E{Key: key, Value: value})
+	}
+
+	s.sort = sort
+	return nil
+}
+
+// SetCursor positions the list after the cursor, the cursor must have been
+// created with the same sort
+func (s *sorting) SetCursor(cursor string) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return errInvalidCursor
+	}
+
+	var token cursorToken
+	if err = bson.Unmarshal(data, &token); err != nil || len(token.Keys) != len(token.Values) {
+		return errInvalidCursor
+	}
+
+	sort := s.GetSort()
+	if !isKeyset(sort) {
+		return errCursorScore
+	}
+	if len(sort) != len(token.Keys) {
+		return errCursorMismatch
+	}
+	for i, field := range sort {
+		if field.Key != token.Keys[i] {
+			return errCursorMismatch
+		}
+	}
+	// the values go into the filter as they are, an operator document or an
+	// array there would change what the filter matches
+	for _, value := range token.Values {
+		if !isScalar(value) {
+			return errInvalidCursor
+		}
+	}
+
+	s.after = token.Values
+	return nil
+}
+
+// checkScoreSort checks a sort on the relevance score, which only a search has
+// and which only makes sense with the best matches first
+func (s *sorting) checkScoreSort(order int) error {
+	if !s.searching {
+		return &model.ParameterError{Parameter: "sort", Message: "cannot sort on score without a search"}
+	}
+	if order != -1 {
+		return &model.ParameterError{Parameter: "sort", Message: "can only sort on score:desc"}
+	}
+	return nil
+}
+
+func (s *sorting) isSortable(key string) bool {
+	for _, field := range s.sortable {
+		if field == key {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCursor reports whether the list is positioned after a cursor
+func (s *sorting) HasCursor() bool {
+	return len(s.after) > 0
+}
+
+func encodeCursor(sort bson.D, doc bson.Raw) (string, error) {
+	token := cursorToken{}
+	for _, field := range sort {
+		token.Keys = append(token.Keys, field.Key)
+
+		value, err := doc.LookupErr(strings.Split(field.Key, ".")...)
+		if err != nil {
+			token.Values = append(token.Values, nil)
+		} else {
+			token.Values = append(token.Values, value)
+		}
+	}
+
+	data, err := bson.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// afterValue returns the condition on a sort field that matches the values
+// after value. Null and missing fields sort before every other value, so
+// nothing comes after null in a descending sort.
+func afterValue(field bson.E, value interface{}) (bson.E, bool) {
+	ascending := field.Value == 1
+	switch {
+	case ascending && value == nil:
+		return bson.E{Key: field.Key, Value: bson.D{{Key: "$ne", Value: nil}}}, true
+	case ascending:
+		return bson.E{Key: field.Key, Value: bson.D{{Key: "$gt", Value: value}}}, true
+	case value == nil:
+		return bson.E{}, false
+	default:
+		return bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: field.Key, Value: bson.D{{Key: "$lt", Value: value}}}},
+			bson.D{{Key: field.Key, Value: nil}},
+		}}, true
+	}
+}
+
+// isKeyset reports whether a cursor can page the sort, a document has no
+// stored key for its relevance score
+func isKeyset(sort bson.D) bool {
+	for _, field := range sort {
+		if field.Key == ScoreKey {
+			return false
+		}
+	}
+	return true
+}
+
+// isScalar reports whether a cursor value is one a sort field can hold, a
+// string, number, boolean, date, ObjectID or null
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case nil, string, bool, int32, int64, float64, primitive.Decimal128, primitive.DateTime, primitive.ObjectID:
+		return true
+	default:
+		return false
+	}
+}
+
+func hasKey(sort bson.D, key string) bool {
+	for _, field := range sort {
+		if field.Key == key {
+			return true
+		}
+	}
+	return false
+}
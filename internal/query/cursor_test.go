@@ -0,0 +1,152 @@
+package query
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGetSort(t *testing.T) {
+	query, err := NewArtworkQuery(map[string][]string{})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "_id", Value: 1}}, query.GetSort())
+
+	query, err = NewArtworkQuery(map[string][]string{}, DeletedSort)
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "deleted_at", Value: -1}, {Key: "_id", Value: 1}}, query.GetSort())
+
+	query, err = NewArtworkQuery(map[string][]string{"sort": {"year:desc", "title:asc"}}, DeletedSort)
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "year", Value: -1}, {Key: "title", Value: 1}, {Key: "_id", Value: 1}}, query.GetSort())
+
+	query, err = NewArtworkQuery(map[string][]string{"sort": {"_id:desc"}})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "_id", Value: -1}}, query.GetSort())
+}
+
+func TestSortErrors(t *testing.T) {
+	testCases := []struct {
+		sort          []string
+		expectedError error
+	}{
+		{
+			sort:          []string{"year"},
+			expectedError: errInvalidSort,
+		},
+		{
+			sort:          []string{":asc"},
+			expectedError: errInvalidSort,
+		},
+		{
+			sort:          []string{"year:up"},
+			expectedError: errInvalidSort,
+		},
+		{
+			sort:          []string{"description:asc"},
+			expectedError: &model.ParameterError{Parameter: "sort", Message: "cannot sort on description, sortable fields are _id, title, year, deleted_at, artist.name"},
+		},
+		{
+			sort:          []string{"year:desc", "title:asc", "year:asc"},
+			expectedError: &model.ParameterError{Parameter: "sort", Message: "sorts on year more than once"},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewArtworkQuery(map[string][]string{"sort": tc.sort})
+		require.Equal(t, tc.expectedError, err)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	last, _ := bson.Marshal(bson.D{{Key: "_id", Value: id}, {Key: "title", Value: "title"}, {Key: "year", Value: 2000}})
+	parameters := map[string][]string{"sort": {"year:desc"}, "limit": {"2"}, "skip": {"4"}}
+
+	query, err := NewArtworkQuery(parameters)
+	require.NoError(t, err)
+
+	page, err := NewPage(1, last, 10, query)
+	require.NoError(t, err)
+	require.Equal(t, "", page.NextCursor)
+
+	page, err = NewPage(2, last, 6, query)
+	require.NoError(t, err)
+	require.Equal(t, "", page.NextCursor)
+
+	page, err = NewPage(2, last, 10, query)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), page.Total)
+	require.Equal(t, int64(2), page.Limit)
+	require.Equal(t, int64(4), page.Skip)
+	require.NotEqual(t, "", page.NextCursor)
+
+	parameters["cursor"] = []string{page.NextCursor}
+	query, err = NewArtworkQuery(parameters)
+	require.NoError(t, err)
+	require.Nil(t, query.GetFindOptions().Skip)
+	require.Equal(t, int64(0), query.GetSkip())
+
+	page, err = NewPage(2, last, 6, query)
+	require.NoError(t, err)
+	require.NotEqual(t, "", page.NextCursor)
+	require.Equal(t, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "year", Value: bson.D{{Key: "$lt", Value: int32(2000)}}}},
+			bson.D{{Key: "year", Value: nil}},
+		}}},
+		bson.D{{Key: "year", Value: int32(2000)}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+	}}}, query.GetCursorFilter())
+}
+
+func TestCursorAfterNull(t *testing.T) {
+	id := primitive.NewObjectID()
+	last, _ := bson.Marshal(bson.D{{Key: "_id", Value: id}})
+	cursor, err := encodeCursor(bson.D{{Key: "year", Value: 1}, {Key: "_id", Value: 1}}, last)
+	require.NoError(t, err)
+
+	query, err := NewArtworkQuery(map[string][]string{"sort": {"year:asc"}, "cursor": {cursor}})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "year", Value: bson.D{{Key: "$ne", Value: nil}}}},
+		bson.D{{Key: "year", Value: nil}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+	}}}, query.GetCursorFilter())
+
+	cursor, err = encodeCursor(bson.D{{Key: "year", Value: -1}, {Key: "_id", Value: 1}}, last)
+	require.NoError(t, err)
+
+	query, err = NewArtworkQuery(map[string][]string{"sort": {"year:desc"}, "cursor": {cursor}})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "year", Value: nil}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+	}}}, query.GetCursorFilter())
+}
+
+func TestInvalidCursor(t *testing.T) {
+	last, _ := bson.Marshal(bson.D{{Key: "_id", Value: primitive.NewObjectID()}})
+	cursor, err := encodeCursor(bson.D{{Key: "_id", Value: 1}}, last)
+	require.NoError(t, err)
+
+	_, err = NewArtistQuery(map[string][]string{"cursor": {"not a cursor"}})
+	require.Equal(t, errInvalidCursor, err)
+
+	_, err = NewExhibitionQuery(map[string][]string{"cursor": {"bm90IGEgY3Vyc29y"}})
+	require.Equal(t, errInvalidCursor, err)
+
+	_, err = NewArtistQuery(map[string][]string{"sort": {"name:asc"}, "cursor": {cursor}})
+	require.Equal(t, errCursorMismatch, err)
+
+	for _, value := range []interface{}{
+		bson.D{{Key: "$exists", Value: true}},
+		bson.A{1, 2},
+		primitive.Regex{Pattern: ".*"},
+	} {
+		data, err := bson.Marshal(cursorToken{Keys: []string{"_id"}, Values: []interface{}{value}})
+		require.NoError(t, err)
+		_, err = NewArtistQuery(map[string][]string{"cursor": {base64.RawURLEncoding.EncodeToString(data)}})
+		require.Equal(t, errInvalidCursor, err)
+	}
+}
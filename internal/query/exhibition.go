@@ -1,79 +1,141 @@
 package query
 
 import (
-	"strconv"
-
 	"github.com/iamnotrodger/art-house-api/cmd/config"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var exhibitionSortable = []string{"_id", "name", "deleted_at"}
+
 type ExhibitionQueryParams struct {
 	limit int64
 	skip  int64
-	sort  map[string]int
+	checking
+	sorting
+	projecting
+	filtering
+	search string
 }
 
-func NewExhibitionQuery(parameters map[string][]string) *ExhibitionQueryParams {
+// NewExhibitionQuery parses the query parameters of a list, defaultSort orders the
+// list when the parameters do not sort it
+func NewExhibitionQuery(parameters map[string][]string, defaultSort ...bson.E) (*ExhibitionQueryParams, error) {
+	params := NewParameters(parameters)
 	query := &ExhibitionQueryParams{}
-
-	if limit, ok := parameters["limit"]; ok {
-		query.setLimitFromString(limit[0])
-	} else {
-		query.limit = config.Global.ExhibitionLimit
+	query.params = params
+	query.defaultSort = defaultSort
+	query.sortable = exhibitionSortable
+	query.selectable = exhibitionSelectable
+	query.filterable = exhibitionFilterable
+
+	query.limit = params.limit("limit", exhibitionLimits())
+	query.skip = params.skip()
+	// envelope is read by the response of the page, it is only checked here
+	params.flag("envelope")
+	query.SetSearch(params.Get("search"))
+	query.searching = query.isSearchValid()
+	if params.Has("sort") {
+		if err := query.SetSort(params.Values("sort")); err != nil {
+			return nil, err
+		}
 	}
-	if skip, ok := parameters["skip"]; ok {
-		query.setSkipFromString(skip[0])
+	if params.Has("cursor") {
+		if err := query.SetCursor(params.Get("cursor")); err != nil {
+			return nil, err
+		}
 	}
-	if sort, ok := parameters["sort"]; ok {
-		query.SetSort(sort)
+	if params.Has("fields") {
+		if err := query.SetFields(params.Values("fields")); err != nil {
+			return nil, err
+		}
+	}
+	if params.Has("filter") {
+		if err := query.SetFilter(params.Get("filter")); err != nil {
+			return nil, err
+		}
 	}
 
-	return query
+	return query, nil
 }
 
 func (q *ExhibitionQueryParams) GetFilter() bson.D {
-	return bson.D{}
+	filter := q.GetCountFilter()
+	filter = append(filter, q.GetCursorFilter()...)
+	return filter
+}
+
+// GetCountFilter matches the whole list, regardless of the page
+func (q *ExhibitionQueryParams) GetCountFilter() bson.D {
+	filter := bson.D{}
+	filter = append(filter, NotDeletedFilter...)
+	if q.isSearchValid() {
+		filter = append(filter, TextFilter(q.search))
+	}
+	filter = append(filter, q.getExpressionFilter()...)
+	return filter
+}
+
+// GetCountPipeline counts the whole list, regardless of the page
+func (q *ExhibitionQueryParams) GetCountPipeline() []bson.D {
+	return CountPipeline(q.GetCountFilter())
 }
 
 func (q *ExhibitionQueryParams) GetFindOptions() *options.FindOptions {
 	options := options.Find()
-	if q.isSortValid() {
-		sort := getSortAsBson(q.sort)
-		options.SetSort(sort)
-	}
+	options.SetSort(q.GetSort())
 	if q.isSkipValid() {
 		options.SetSkip(q.skip)
 	}
 	options.SetLimit(q.limit)
+	if projection := q.GetProjection(); len(projection) > 0 {
+		options.SetProjection(projection)
+	}
 	return options
 }
 
 func (q *ExhibitionQueryParams) GetPipeline() []bson.D {
 	pipeline := []bson.D{}
 
-	if q.isSortValid() {
-		sort := bson.D{{Key: "$sort", Value: q.sort}}
-		pipeline = append(pipeline, sort)
-	}
+	match := bson.D{{Key: "$match", Value: q.GetFilter()}}
+	pipeline = append(pipeline, match)
+
+	sort := bson.D{{Key: "$sort", Value: q.GetSort()}}
+	pipeline = append(pipeline, sort)
 	if q.isSkipValid() {
 		skip := bson.D{{Key: "$skip", Value: q.skip}}
 		pipeline = append(pipeline, skip)
 	}
 	limit := bson.D{{Key: "$limit", Value: q.limit}}
 	pipeline = append(pipeline, limit)
+	if projection := q.GetProjection(); len(projection) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: projection}})
+	}
 
 	return pipeline
 }
 
-func (q *ExhibitionQueryParams) SetLimit(limit int64) {
-	if limit < config.Global.ExhibitionLimitMin {
-		q.limit = config.Global.ExhibitionLimit
-	} else if limit > config.Global.ExhibitionLimitMax {
-		q.limit = config.Global.ExhibitionLimitMax
-	} else {
-		q.limit = limit
+// GetProjection returns the projection of the selected fields, it is empty
+// when every field is returned
+func (q *ExhibitionQueryParams) GetProjection() bson.D {
+	return q.projection(q.GetSort())
+}
+
+func (q *ExhibitionQueryParams) GetLimit() int64 {
+	return q.limit
+}
+
+// GetSkip returns the number of documents skipped, which is none when a cursor
+// positions the list
+func (q *ExhibitionQueryParams) GetSkip() int64 {
+	if !q.isSkipValid() {
+		return 0
 	}
+	return q.skip
+}
+
+func (q *ExhibitionQueryParams) SetLimit(limit int64) {
+	q.limit = exhibitionLimits().clamp(limit)
 }
 func (q *ExhibitionQueryParams) SetSkip(skip int64) {
 	if skip > 0 {
@@ -81,36 +143,25 @@ func (q *ExhibitionQueryParams) SetSkip(skip int64) {
 	}
 }
 
-func (q *ExhibitionQueryParams) SetSort(sortArray []string) {
-	q.sort = map[string]int{}
-	for _, sortString := range sortArray {
-		key, value := parseSort(sortString)
-		if key != "" {
-			q.sort[key] = value
-		}
-	}
-}
-
-func (q *ExhibitionQueryParams) setLimitFromString(limitString string) {
-	limit, err := strconv.ParseInt(limitString, 0, 64)
-	if err != nil {
-		q.limit = config.Global.ExhibitionLimit
-	} else {
-		q.SetLimit(limit)
+func (q *ExhibitionQueryParams) SetSearch(search string) {
+	if search != "" {
+		q.search = search
 	}
 }
 
-func (q *ExhibitionQueryParams) setSkipFromString(skipString string) {
-	skip, err := strconv.ParseInt(skipString, 0, 64)
-	if err == nil {
-		q.SetSkip(skip)
-	}
+// isSkipValid reports whether to skip, a cursor positions the list by itself
+func (q *ExhibitionQueryParams) isSkipValid() bool {
+	return q.skip > 0 && !q.HasCursor()
 }
 
-func (q *ExhibitionQueryParams) isSortValid() bool {
-	return q.sort != nil && len(q.sort) > 0
+func (q *ExhibitionQueryParams) isSearchValid() bool {
+	return q.search != ""
 }
 
-func (q *ExhibitionQueryParams) isSkipValid() bool {
-	return q.skip > 0
+func exhibitionLimits() limits {
+	return limits{
+		value: config.Global.ExhibitionLimit,
+		min:   config.Global.ExhibitionLimitMin,
+		max:   config.Global.ExhibitionLimitMax,
+	}
 }
@@ -0,0 +1,75 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+)
+
+// exhibitionRelations are the relations an exhibition can embed with expand=
+var exhibitionRelations = []string{"artists", "artworks"}
+
+// The fields of the summaries that are embedded for a relation
+var (
+	artworkSummary = []string{"title", "year", "images", "artist._id", "artist.name"}
+	artistSummary  = []string{"name", "images"}
+)
+
+// ExhibitionExpand holds the queries of the relations an exhibition embeds
+type ExhibitionExpand struct {
+	checking
+	artworks *ArtworkQueryParams
+	artists  *ArtistQueryParams
+}
+
+// NewExhibitionExpand parses expand=artists,artworks and the limits of the
+// relations, artists_limit and artworks_limit. A relation embeds summaries of
+// the first documents of the exhibition's list, artworks in their curated order.
+func NewExhibitionExpand(parameters map[string][]string) (*ExhibitionExpand, error) {
+	params := NewParameters(parameters)
+	expand := &ExhibitionExpand{}
+	expand.params = params
+
+	relations, err := params.Choices("expand", exhibitionRelations)
+	if err != nil {
+		return nil, err
+	}
+	for _, relation := range relations {
+		relationParameters := map[string][]string{}
+		limit := relation + "_limit"
+
+		switch relation {
+		case "artworks":
+			if params.Has(limit) {
+				relationParameters["limit"] = []string{strconv.FormatInt(params.limit(limit, artworkLimits()), 10)}
+			}
+			relationParameters["fields"] = []string{strings.Join(artworkSummary, ",")}
+			expand.artworks, err = NewArtworkQuery(relationParameters, CuratedSort)
+		case "artists":
+			if params.Has(limit) {
+				relationParameters["limit"] = []string{strconv.FormatInt(params.limit(limit, artistLimits()), 10)}
+			}
+			relationParameters["fields"] = []string{strings.Join(artistSummary, ",")}
+			expand.artists, err = NewArtistQuery(relationParameters)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return expand, nil
+}
+
+// IsEmpty reports whether the exhibition embeds no relation
+func (e *ExhibitionExpand) IsEmpty() bool {
+	return e.artworks == nil && e.artists == nil
+}
+
+// GetArtworkQuery returns the query of the embedded artworks, nil unless they are expanded
+func (e *ExhibitionExpand) GetArtworkQuery() *ArtworkQueryParams {
+	return e.artworks
+}
+
+// GetArtistQuery returns the query of the embedded artists, nil unless they are expanded
+func (e *ExhibitionExpand) GetArtistQuery() *ArtistQueryParams {
+	return e.artists
+}
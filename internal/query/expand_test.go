@@ -0,0 +1,41 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestExhibitionExpand(t *testing.T) {
+	expand, err := NewExhibitionExpand(map[string][]string{})
+	require.NoError(t, err)
+	require.True(t, expand.IsEmpty())
+
+	expand, err = NewExhibitionExpand(map[string][]string{"expand": {"artworks"}, "artworks_limit": {"3"}, "artists_limit": {"2"}})
+	require.NoError(t, err)
+	require.False(t, expand.IsEmpty())
+	require.Nil(t, expand.GetArtistQuery())
+
+	artworks := expand.GetArtworkQuery()
+	require.Equal(t, int64(3), artworks.GetLimit())
+	require.Equal(t, bson.D{CuratedSort, {Key: "_id", Value: 1}}, artworks.GetSort())
+	require.Equal(t, bson.D{
+		{Key: "title", Value: 1},
+		{Key: "year", Value: 1},
+		{Key: "images", Value: 1},
+		{Key: "artist._id", Value: 1},
+		{Key: "artist.name", Value: 1},
+		{Key: "position", Value: 1},
+		{Key: "_id", Value: 1},
+	}, artworks.GetProjection())
+
+	expand, err = NewExhibitionExpand(map[string][]string{"expand": {"artists"}})
+	require.NoError(t, err)
+	require.Nil(t, expand.GetArtworkQuery())
+	require.Equal(t, int64(15), expand.GetArtistQuery().GetLimit())
+
+	_, err = NewExhibitionExpand(map[string][]string{"expand": {"artists,curator"}})
+	require.Equal(t, &model.ParameterError{Parameter: "expand", Message: "must be one of artists, artworks"}, err)
+}
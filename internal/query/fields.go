@@ -0,0 +1,85 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	artworkSelectable    = []string{"_id", "title", "images", "year", "description", "artist", "version", "deleted_at"}
+	artistSelectable     = []string{"_id", "name", "images", "version", "deleted_at"}
+	exhibitionSelectable = []string{"_id", "name", "description", "images", "version", "deleted_at"}
+)
+
+// projecting selects the fields of the documents of a list, every field is
+// returned unless some are selected
+type projecting struct {
+	fields []string
+	// selectable lists the top level fields that can be selected, along with
+	// the fields inside them as dotted paths
+	selectable []string
+}
+
+// SetFields reads the fields from fields=title,artist.name or from repeated parameters
+func (p *projecting) SetFields(values []string) error {
+	p.fields = nil
+	for _, value := range values {
+		for _, field := range strings.Split(value, ",") {
+			if !p.isSelectable(field) {
+				message := "cannot select " + field + ", selectable fields are " + strings.Join(p.selectable, ", ")
+				return &model.ParameterError{Parameter: "fields", Message: message}
+			}
+			p.fields = append(p.fields, field)
+		}
+	}
+	return nil
+}
+
+// projection returns the projection of the selected fields, which keeps the
+// keys of the sort so that the last document of a page can still be turned
+// into a cursor. It is empty when every field is returned.
+func (p *projecting) projection(sort bson.D) bson.D {
+	if len(p.fields) == 0 {
+		return nil
+	}
+
+	paths := append([]string{}, p.fields...)
+	for _, field := range sort {
+		if field.Key != ScoreKey {
+			paths = append(paths, field.Key)
+		}
+	}
+
+	// a path inside another selected path would collide with it
+	projection := bson.D{}
+	for i, path := range paths {
+		covered := false
+		for j, other := range paths {
+			if i != j && isPathWithin(path, other) && (path != other || j < i) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			projection = append(projection, bson.E{Key: path, Value: 1})
+		}
+	}
+	return projection
+}
+
+func (p *projecting) isSelectable(field string) bool {
+	segments := strings.Split(field, ".")
+	for _, segment := range segments {
+		if segment == "" || strings.HasPrefix(segment, "$") {
+			return false
+		}
+	}
+	return contains(p.selectable, segments[0])
+}
+
+// isPathWithin reports whether path is parent or a path inside it
+func isPathWithin(path string, parent string) bool {
+	return path == parent || strings.HasPrefix(path, parent+".")
+}
@@ -0,0 +1,74 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestProjection(t *testing.T) {
+	query, err := NewArtworkQuery(map[string][]string{})
+	require.NoError(t, err)
+	require.Nil(t, query.GetProjection())
+	require.Nil(t, query.GetFindOptions().Projection)
+
+	query, err = NewArtworkQuery(map[string][]string{"fields": {"title,artist.name", "images.url"}, "sort": {"year:desc"}})
+	require.NoError(t, err)
+	projection := bson.D{
+		{Key: "title", Value: 1},
+		{Key: "artist.name", Value: 1},
+		{Key: "images.url", Value: 1},
+		{Key: "year", Value: 1},
+		{Key: "_id", Value: 1},
+	}
+	require.Equal(t, projection, query.GetProjection())
+	require.Equal(t, projection, query.GetFindOptions().Projection)
+
+	pipeline := query.GetPipeline()
+	require.Equal(t, []bson.D{ArtworkLookupStage, ArtworkUnwindStage}, pipeline[len(pipeline)-3:len(pipeline)-1])
+	require.Equal(t, bson.D{{Key: "$project", Value: projection}}, pipeline[len(pipeline)-1])
+	require.Equal(t, pipeline, JoinArtist(pipeline))
+
+	query, err = NewArtworkQuery(map[string][]string{"fields": {"artist,artist.name,title,title"}, "sort": {"artist.name:asc"}})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{
+		{Key: "artist", Value: 1},
+		{Key: "title", Value: 1},
+		{Key: "_id", Value: 1},
+	}, query.GetProjection())
+
+	artists, err := NewArtistQuery(map[string][]string{"fields": {"name"}, "search": {"monet"}, "sort": {"score:desc"}})
+	require.NoError(t, err)
+	pipeline = artists.GetPipeline()
+	require.Equal(t, bson.D{{Key: "$project", Value: bson.D{
+		{Key: "name", Value: 1},
+		{Key: "_id", Value: 1},
+	}}}, pipeline[len(pipeline)-1])
+}
+
+func TestFieldsErrors(t *testing.T) {
+	testCases := []struct {
+		fields        []string
+		expectedError error
+	}{
+		{
+			fields:        []string{"name,secret"},
+			expectedError: &model.ParameterError{Parameter: "fields", Message: "cannot select secret, selectable fields are _id, name, description, images, version, deleted_at"},
+		},
+		{
+			fields:        []string{"name,"},
+			expectedError: &model.ParameterError{Parameter: "fields", Message: "cannot select , selectable fields are _id, name, description, images, version, deleted_at"},
+		},
+		{
+			fields:        []string{"images.$"},
+			expectedError: &model.ParameterError{Parameter: "fields", Message: "cannot select images.$, selectable fields are _id, name, description, images, version, deleted_at"},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewExhibitionQuery(map[string][]string{"fields": tc.fields})
+		require.Equal(t, tc.expectedError, err)
+	}
+}
@@ -0,0 +1,72 @@
+package query
+
+import (
+	"context"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CountStage counts the documents of a list into a total field
+var CountStage = bson.D{{Key: "$count", Value: "total"}}
+
+type countResult struct {
+	Total int64 `bson:"total"`
+}
+
+// NewPage returns the page of count documents a list query found, ending with
+// last, out of the total documents of the list. A full page that does not
+// reach the end of the list has a cursor to the next page; after a cursor the
+// end cannot be told apart from a full page, so the next page can be empty.
+func NewPage(count int, last bson.Raw, total int64, queryParam ...QueryParams) (*model.Page, error) {
+	page := &model.Page{Total: total}
+	if len(queryParam) == 0 {
+		return page, nil
+	}
+
+	q := queryParam[0]
+	page.Limit = q.GetLimit()
+	page.Skip = q.GetSkip()
+	if count == 0 || int64(count) < page.Limit {
+		return page, nil
+	}
+	if !q.HasCursor() && page.Skip+int64(count) >= total {
+		return page, nil
+	}
+	if !isKeyset(q.GetSort()) {
+		return page, nil
+	}
+
+	cursor, err := encodeCursor(q.GetSort(), last)
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = cursor
+
+	return page, nil
+}
+
+// CountPipeline counts the documents that match filter
+func CountPipeline(filter bson.D) []bson.D {
+	match := bson.D{{Key: "$match", Value: filter}}
+	return []bson.D{match, CountStage}
+}
+
+// Count runs a pipeline that ends with CountStage and returns the count
+func Count(ctx context.Context, collection *mongo.Collection, pipeline []bson.D) (int64, error) {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result countResult
+	if cursor.Next(ctx) {
+		if err = cursor.Decode(&result); err != nil {
+			return 0, err
+		}
+	}
+
+	return result.Total, cursor.Err()
+}
@@ -0,0 +1,234 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Parameters reads the query parameters of a request as the type of each
+// parameter. It holds a copy of the parameters it is made from, so reading
+// them never changes the request, and a parameter is never passed on to mongo
+// as it is.
+//
+// The values that are read leniently, such as a limit that is not a number,
+// fall back to a default. They are kept as problems along with the parameters
+// that were never read, which strict checking rejects.
+type Parameters struct {
+	values   map[string][]string
+	read     map[string]bool
+	problems []*model.ParameterError
+}
+
+// limits are the default limit of a list and the range it is kept within
+type limits struct {
+	value int64
+	min   int64
+	max   int64
+}
+
+func NewParameters(values map[string][]string) *Parameters {
+	copied := make(map[string][]string, len(values))
+	for name, value := range values {
+		copied[name] = append([]string{}, value...)
+	}
+	return &Parameters{values: copied, read: map[string]bool{}}
+}
+
+func (p *Parameters) Has(name string) bool {
+	p.read[name] = true
+	_, ok := p.values[name]
+	return ok
+}
+
+// Get returns the first value of a parameter, which is empty when it is missing
+func (p *Parameters) Get(name string) string {
+	p.read[name] = true
+	if values := p.values[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Values returns every value of a repeated parameter
+func (p *Parameters) Values(name string) []string {
+	p.read[name] = true
+	return append([]string{}, p.values[name]...)
+}
+
+// Int returns a parameter as a whole number, ok is false when it is missing
+func (p *Parameters) Int(name string) (value int64, ok bool, err error) {
+	if !p.Has(name) {
+		return 0, false, nil
+	}
+	value, err = strconv.ParseInt(p.Get(name), 10, 64)
+	if err != nil {
+		return 0, false, &model.ParameterError{Parameter: name, Message: "must be a number"}
+	}
+	return value, true, nil
+}
+
+// Bool returns a parameter as true or false, nil when it is missing
+func (p *Parameters) Bool(name string) (*bool, error) {
+	if !p.Has(name) {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(p.Get(name))
+	if err != nil {
+		return nil, &model.ParameterError{Parameter: name, Message: "must be true or false"}
+	}
+	return &value, nil
+}
+
+// ID returns a parameter as an ID, the zero ID when it is missing
+func (p *Parameters) ID(name string) (primitive.ObjectID, error) {
+	if !p.Has(name) {
+		return primitive.NilObjectID, nil
+	}
+	return parseID(name, p.Get(name))
+}
+
+// IDs returns every value of a repeated parameter as an ID
+func (p *Parameters) IDs(name string) ([]primitive.ObjectID, error) {
+	ids := []primitive.ObjectID{}
+	for _, value := range p.Values(name) {
+		id, err := parseID(name, value)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Choices reads the values of a parameter that picks among choices, as in
+// facets=decade,artist or from repeated parameters. The picked values are
+// returned once each in the order of the choices.
+func (p *Parameters) Choices(name string, choices []string) ([]string, error) {
+	picked := []string{}
+	for _, value := range p.Values(name) {
+		for _, choice := range strings.Split(value, ",") {
+			if !contains(choices, choice) {
+				message := "must be one of " + strings.Join(choices, ", ")
+				return nil, &model.ParameterError{Parameter: name, Message: message}
+			}
+			picked = append(picked, choice)
+		}
+	}
+
+	ordered := []string{}
+	for _, choice := range choices {
+		if contains(picked, choice) {
+			ordered = append(ordered, choice)
+		}
+	}
+	return ordered, nil
+}
+
+// limit returns the limit of a list kept within its limits. A limit that is
+// not a number is the default limit.
+func (p *Parameters) limit(name string, l limits) int64 {
+	limit, ok, err := p.Int(name)
+	if err != nil {
+		p.problems = append(p.problems, withReason(err, model.ReasonInvalid))
+		return l.value
+	} else if !ok {
+		return l.value
+	}
+
+	if limit < l.min || limit > l.max {
+		message := fmt.Sprintf("must be between %d and %d", l.min, l.max)
+		p.problems = append(p.problems, &model.ParameterError{Parameter: name, Reason: model.ReasonOutOfRange, Message: message})
+	}
+	return l.clamp(limit)
+}
+
+// skip returns the number of documents to skip, which is none unless it is a
+// positive number
+func (p *Parameters) skip() int64 {
+	skip, _, err := p.Int("skip")
+	if err != nil {
+		p.problems = append(p.problems, withReason(err, model.ReasonInvalid))
+		return 0
+	}
+	if skip < 0 {
+		p.problems = append(p.problems, &model.ParameterError{Parameter: "skip", Reason: model.ReasonOutOfRange, Message: "must not be negative"})
+		return 0
+	}
+	return skip
+}
+
+// flag reads a parameter that is true or false, a value that is neither is false
+func (p *Parameters) flag(name string) bool {
+	value, err := p.Bool(name)
+	if err != nil {
+		p.problems = append(p.problems, withReason(err, model.ReasonInvalid))
+		return false
+	}
+	return value != nil && *value
+}
+
+// check reports the values that were read leniently and the parameters that
+// were never read, every parameter that a query does not know is unknown
+func (p *Parameters) check() error {
+	errs := append([]*model.ParameterError{}, p.problems...)
+
+	unknown := []string{}
+	for name := range p.values {
+		if !p.read[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, &model.ParameterError{Parameter: name, Reason: model.ReasonUnknown, Message: "is not a parameter of this request"})
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &model.ParametersError{Errors: errs}
+}
+
+// clamp keeps a limit within the range, a limit below it is the default limit
+func (l limits) clamp(limit int64) int64 {
+	if limit < l.min {
+		return l.value
+	} else if limit > l.max {
+		return l.max
+	}
+	return limit
+}
+
+func parseID(parameter string, value string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return primitive.NilObjectID, &model.ParameterError{Parameter: parameter, Message: "must be an ID"}
+	}
+	return id, nil
+}
+
+func withReason(err error, reason string) *model.ParameterError {
+	parameterErr := *err.(*model.ParameterError)
+	parameterErr.Reason = reason
+	return &parameterErr
+}
+
+// checking keeps the parameters a query is parsed from so that they can be
+// checked strictly once it is parsed
+type checking struct {
+	params *Parameters
+}
+
+// CheckParameters rejects every parameter that the query only read leniently
+// or did not read at all, it reports each of them with the reason
+func (c *checking) CheckParameters() error {
+	if c.params == nil {
+		return nil
+	}
+	return c.params.check()
+}
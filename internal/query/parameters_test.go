@@ -0,0 +1,115 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/cmd/config"
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParameters(t *testing.T) {
+	id := primitive.NewObjectID()
+	values := map[string][]string{
+		"year":   {"1889"},
+		"flag":   {"true"},
+		"id":     {id.Hex(), id.Hex()},
+		"facets": {"artist,decade"},
+		"$where": {"sleep(1000)"},
+		"title":  {"Starry Night"},
+		"decade": {"1880s"},
+	}
+	params := NewParameters(values)
+
+	year, ok, err := params.Int("year")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, int64(1889), year)
+	_, ok, err = params.Int("missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, _, err = params.Int("decade")
+	require.Equal(t, &model.ParameterError{Parameter: "decade", Message: "must be a number"}, err)
+
+	flag, err := params.Bool("flag")
+	require.NoError(t, err)
+	require.True(t, *flag)
+	_, err = params.Bool("title")
+	require.Equal(t, &model.ParameterError{Parameter: "title", Message: "must be true or false"}, err)
+
+	ids, err := params.IDs("id")
+	require.NoError(t, err)
+	require.Equal(t, []primitive.ObjectID{id, id}, ids)
+	_, err = params.ID("title")
+	require.Equal(t, &model.ParameterError{Parameter: "title", Message: "must be an ID"}, err)
+
+	facets, err := params.Choices("facets", artworkFacets)
+	require.NoError(t, err)
+	require.Equal(t, []string{"decade", "artist"}, facets)
+
+	// reading the parameters never changes them
+	params.Values("id")[0] = "changed"
+	values["title"][0] = "changed"
+	delete(values, "year")
+	require.Equal(t, id.Hex(), params.Get("id"))
+	require.Equal(t, "Starry Night", params.Get("title"))
+	require.True(t, params.Has("year"))
+	require.Equal(t, []string{"sleep(1000)"}, values["$where"])
+}
+
+func TestParametersLimit(t *testing.T) {
+	artworks := artworkLimits()
+	testCases := []struct {
+		parameters    map[string][]string
+		expectedLimit int64
+		expectedSkip  int64
+	}{
+		{parameters: map[string][]string{}, expectedLimit: config.Global.ArtworkLimit},
+		{parameters: map[string][]string{"limit": {"3"}, "skip": {"6"}}, expectedLimit: 3, expectedSkip: 6},
+		{parameters: map[string][]string{"limit": {"many"}, "skip": {"-1"}}, expectedLimit: config.Global.ArtworkLimit},
+		{parameters: map[string][]string{"limit": {"0"}}, expectedLimit: config.Global.ArtworkLimit},
+		{parameters: map[string][]string{"limit": {"100000"}}, expectedLimit: config.Global.ArtworkLimitMax},
+	}
+
+	for _, tc := range testCases {
+		params := NewParameters(tc.parameters)
+		require.Equal(t, tc.expectedLimit, params.limit("limit", artworks))
+		require.Equal(t, tc.expectedSkip, params.skip())
+	}
+
+	artists, err := NewArtistQuery(map[string][]string{"limit": {"100000"}})
+	require.NoError(t, err)
+	require.Equal(t, config.Global.ArtistLimitMax, artists.GetLimit())
+}
+
+func TestCheckParameters(t *testing.T) {
+	query, err := NewArtworkQuery(map[string][]string{"limit": {"2"}, "sort": {"year:desc"}, "envelope": {"true"}})
+	require.NoError(t, err)
+	require.NoError(t, query.CheckParameters())
+
+	query, err = NewArtworkQuery(map[string][]string{
+		"limit":    {"many"},
+		"skip":     {"-2"},
+		"envelope": {"yes"},
+		"year":     {"1889"},
+		"$where":   {"1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, config.Global.ArtworkLimit, query.GetLimit())
+	require.Equal(t, &model.ParametersError{Errors: []*model.ParameterError{
+		{Parameter: "limit", Reason: model.ReasonInvalid, Message: "must be a number"},
+		{Parameter: "skip", Reason: model.ReasonOutOfRange, Message: "must not be negative"},
+		{Parameter: "envelope", Reason: model.ReasonInvalid, Message: "must be true or false"},
+		{Parameter: "$where", Reason: model.ReasonUnknown, Message: "is not a parameter of this request"},
+		{Parameter: "year", Reason: model.ReasonUnknown, Message: "is not a parameter of this request"},
+	}}, query.CheckParameters())
+
+	expand, err := NewExhibitionExpand(map[string][]string{"expand": {"artworks"}, "artworks_limit": {"500"}, "artists_limit": {"2"}})
+	require.NoError(t, err)
+	require.Equal(t, config.Global.ArtworkLimitMax, expand.GetArtworkQuery().GetLimit())
+	require.Equal(t, &model.ParametersError{Errors: []*model.ParameterError{
+		{Parameter: "artworks_limit", Reason: model.ReasonOutOfRange, Message: "must be between 1 and 100"},
+		{Parameter: "artists_limit", Reason: model.ReasonUnknown, Message: "is not a parameter of this request"},
+	}}, expand.CheckParameters())
+}
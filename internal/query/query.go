@@ -7,6 +7,12 @@ import (
 
 type QueryParams interface {
 	GetFilter() bson.D
+	GetCountPipeline() []bson.D
+	GetCursorFilter() bson.D
 	GetFindOptions() *options.FindOptions
 	GetPipeline() []bson.D
+	GetSort() bson.D
+	GetLimit() int64
+	GetSkip() int64
+	HasCursor() bool
 }
@@ -0,0 +1,414 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FieldType is the type that the values of a filtered field are parsed as
+type FieldType int
+
+const (
+	StringField FieldType = iota
+	IntField
+	IDField
+)
+
+const (
+	// maxFilterLength caps the length of a filter expression
+	maxFilterLength = 1000
+	// maxFilterDepth caps how deep the groups of a filter expression nest
+	maxFilterDepth = 10
+)
+
+var (
+	artworkFilterable = map[string]FieldType{
+		"_id":         IDField,
+		"title":       StringField,
+		"year":        IntField,
+		"description": StringField,
+		"artist_id":   IDField,
+		"artist.name": StringField,
+	}
+	artistFilterable = map[string]FieldType{
+		"_id":  IDField,
+		"name": StringField,
+	}
+	exhibitionFilterable = map[string]FieldType{
+		"_id":         IDField,
+		"name":        StringField,
+		"description": StringField,
+	}
+)
+
+// comparisonOperators maps the operators of the filter language to mongo's,
+// == and != are compiled apart since they can hold wildcards
+var comparisonOperators = map[string]string{
+	"=lt=":  "$lt",
+	"=le=":  "$lte",
+	"=gt=":  "$gt",
+	"=ge=":  "$gte",
+	"=in=":  "$in",
+	"=out=": "$nin",
+	"<":     "$lt",
+	"<=":    "$lte",
+	">":     "$gt",
+	">=":    "$gte",
+}
+
+// filtering narrows a list down with a filter expression in the RSQL/FIQL
+// filter language, as in filter=year=ge=1500;artist.name==*Gogh*
+//
+//	expression = or
+//	or         = and { "," and }
+//	and        = group { ";" group }
+//	group      = "(" or ")" | comparison
+//	comparison = field operator ( value | "(" value { "," value } ")" )
+//	operator   = "==" | "!=" | "=lt=" | "=le=" | "=gt=" | "=ge=" | "=in=" | "=out=" | "<" | "<=" | ">" | ">="
+//	value      = unreserved characters | a '' or "" quoted string
+//
+// Only the fields of the allowlist can be filtered on, and their values are
+// parsed as the type of the field. A * in the value of == or != on a text
+// field matches any text.
+type filtering struct {
+	expression bson.D
+	// paths are the fields that the expression filters on
+	paths []string
+	// filterable maps the fields that can be filtered on to their type
+	filterable map[string]FieldType
+}
+
+// SetFilter compiles a filter expression, it reports the position of the
+// first character that cannot be parsed
+func (f *filtering) SetFilter(expression string) error {
+	compiled, paths, err := compileFilter(expression, f.filterable)
+	if err != nil {
+		return err
+	}
+	f.expression = compiled
+	f.paths = paths
+	return nil
+}
+
+// getExpressionFilter returns the compiled filter expression. It is wrapped
+// in an $and so that it does not collide with the other conditions of a filter.
+func (f *filtering) getExpressionFilter() bson.D {
+	if len(f.expression) == 0 {
+		return bson.D{}
+	}
+	return bson.D{{Key: "$and", Value: bson.A{f.expression}}}
+}
+
+// filtersOn reports whether the filter expression has a field that starts with prefix
+func (f *filtering) filtersOn(prefix string) bool {
+	for _, path := range f.paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileFilter compiles a filter expression against the fields it can filter
+// on, and returns the fields that it filters on
+func compileFilter(expression string, filterable map[string]FieldType) (bson.D, []string, error) {
+	p := &filterParser{input: []rune(expression), filterable: filterable}
+	if len(p.input) == 0 {
+		return nil, nil, p.errorf(0, "is empty")
+	}
+	if len(p.input) > maxFilterLength {
+		return nil, nil, &model.ParameterError{
+			Parameter: "filter",
+			Message:   fmt.Sprintf("is longer than %d characters", maxFilterLength),
+		}
+	}
+
+	compiled, err := p.parseOr(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !p.done() {
+		return nil, nil, p.errorf(p.pos, "has an unexpected %q", p.input[p.pos])
+	}
+
+	return compiled, p.paths, nil
+}
+
+type filterParser struct {
+	input      []rune
+	pos        int
+	filterable map[string]FieldType
+	paths      []string
+}
+
+func (p *filterParser) parseOr(depth int) (bson.D, error) {
+	return p.parseList(depth, ',', "$or", p.parseAnd)
+}
+
+func (p *filterParser) parseAnd(depth int) (bson.D, error) {
+	return p.parseList(depth, ';', "$and", p.parseGroup)
+}
+
+// parseList parses the operands of a logical operator, a single operand is
+// returned as it is
+func (p *filterParser) parseList(depth int, separator rune, operator string, parseOperand func(int) (bson.D, error)) (bson.D, error) {
+	operand, err := parseOperand(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	operands := bson.A{operand}
+	for p.peek(separator) {
+		p.pos++
+		operand, err = parseOperand(depth)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+
+	if len(operands) == 1 {
+		return operand, nil
+	}
+	return bson.D{{Key: operator, Value: operands}}, nil
+}
+
+func (p *filterParser) parseGroup(depth int) (bson.D, error) {
+	if !p.peek('(') {
+		return p.parseComparison()
+	}
+	if depth >= maxFilterDepth {
+		return nil, p.errorf(p.pos, "nests groups more than %d deep", maxFilterDepth)
+	}
+
+	start := p.pos
+	p.pos++
+	group, err := p.parseOr(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	if !p.peek(')') {
+		return nil, p.errorf(start, "has an unclosed (")
+	}
+	p.pos++
+	return group, nil
+}
+
+func (p *filterParser) parseComparison() (bson.D, error) {
+	start := p.pos
+	for !p.done() && isFieldRune(p.input[p.pos]) {
+		p.pos++
+	}
+	field := string(p.input[start:p.pos])
+	if field == "" {
+		return nil, p.errorf(start, "expects a field")
+	}
+	fieldType, ok := p.filterable[field]
+	if !ok {
+		return nil, p.errorf(start, "cannot filter on %s, filterable fields are %s", field, strings.Join(filterableFields(p.filterable), ", "))
+	}
+	p.paths = append(p.paths, field)
+
+	operatorStart := p.pos
+	operator := p.parseOperator()
+	if operator == "" {
+		return nil, p.errorf(operatorStart, "expects an operator")
+	}
+
+	if operator == "=in=" || operator == "=out=" {
+		values, err := p.parseValues(fieldType)
+		if err != nil {
+			return nil, err
+		}
+		condition := bson.D{{Key: comparisonOperators[operator], Value: values}}
+		return bson.D{{Key: field, Value: condition}}, nil
+	}
+
+	valueStart := p.pos
+	value, quoted, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if (operator == "==" || operator == "!=") && !quoted && strings.Contains(value, "*") {
+		if fieldType != StringField {
+			return nil, p.errorf(valueStart, "can only match * on a text field")
+		}
+		pattern := wildcardRegex(value)
+		if operator == "!=" {
+			return bson.D{{Key: field, Value: bson.D{{Key: "$not", Value: pattern}}}}, nil
+		}
+		return bson.D{{Key: field, Value: pattern}}, nil
+	}
+
+	typed, err := p.convert(valueStart, fieldType, value)
+	if err != nil {
+		return nil, err
+	}
+	switch operator {
+	case "==":
+		return bson.D{{Key: field, Value: typed}}, nil
+	case "!=":
+		return bson.D{{Key: field, Value: bson.D{{Key: "$ne", Value: typed}}}}, nil
+	}
+	condition := bson.D{{Key: comparisonOperators[operator], Value: typed}}
+	return bson.D{{Key: field, Value: condition}}, nil
+}
+
+// parseOperator returns the operator at the position, or nothing when there is none
+func (p *filterParser) parseOperator() string {
+	rest := string(p.input[p.pos:])
+	if strings.HasPrefix(rest, "==") || strings.HasPrefix(rest, "!=") {
+		p.pos += 2
+		return rest[:2]
+	}
+
+	operators := []string{}
+	for operator := range comparisonOperators {
+		operators = append(operators, operator)
+	}
+	// longer operators first, so that <= is not read as <
+	sort.Slice(operators, func(i, j int) bool {
+		return len(operators[i]) > len(operators[j])
+	})
+	for _, operator := range operators {
+		if strings.HasPrefix(rest, operator) {
+			p.pos += len([]rune(operator))
+			return operator
+		}
+	}
+	return ""
+}
+
+func (p *filterParser) parseValues(fieldType FieldType) (bson.A, error) {
+	if !p.peek('(') {
+		return nil, p.errorf(p.pos, "expects a ( of values")
+	}
+	start := p.pos
+	p.pos++
+
+	values := bson.A{}
+	for {
+		valueStart := p.pos
+		value, _, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		typed, err := p.convert(valueStart, fieldType, value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, typed)
+
+		if p.peek(',') {
+			p.pos++
+			continue
+		}
+		if p.peek(')') {
+			p.pos++
+			return values, nil
+		}
+		return nil, p.errorf(start, "has an unclosed (")
+	}
+}
+
+// parseValue returns the value at the position and whether it was quoted
+func (p *filterParser) parseValue() (string, bool, error) {
+	start := p.pos
+	if p.peek('\'') || p.peek('"') {
+		quote := p.input[p.pos]
+		p.pos++
+
+		var value strings.Builder
+		for !p.done() {
+			r := p.input[p.pos]
+			p.pos++
+			if r == quote {
+				return value.String(), true, nil
+			}
+			if r == '\\' && !p.done() {
+				r = p.input[p.pos]
+				p.pos++
+			}
+			value.WriteRune(r)
+		}
+		return "", false, p.errorf(start, "has an unclosed quote")
+	}
+
+	for !p.done() && !isReservedRune(p.input[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", false, p.errorf(start, "expects a value")
+	}
+	return string(p.input[start:p.pos]), false, nil
+}
+
+// convert parses a value as the type of its field
+func (p *filterParser) convert(pos int, fieldType FieldType, value string) (interface{}, error) {
+	switch fieldType {
+	case IntField:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, p.errorf(pos, "expects a number")
+		}
+		return number, nil
+	case IDField:
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return nil, p.errorf(pos, "expects an ID")
+		}
+		return id, nil
+	}
+	return value, nil
+}
+
+func (p *filterParser) peek(r rune) bool {
+	return !p.done() && p.input[p.pos] == r
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+// errorf reports a parse error at a position, counted from 1
+func (p *filterParser) errorf(pos int, format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+	return &model.ParameterError{
+		Parameter: "filter",
+		Message:   fmt.Sprintf("%s at position %d", message, pos+1),
+	}
+}
+
+// wildcardRegex matches a text in which each * can be any text, ignoring case
+func wildcardRegex(value string) primitive.Regex {
+	parts := strings.Split(value, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return primitive.Regex{Pattern: "^" + strings.Join(parts, ".*") + "$", Options: "i"}
+}
+
+func filterableFields(filterable map[string]FieldType) []string {
+	fields := []string{}
+	for field := range filterable {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func isFieldRune(r rune) bool {
+	return r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+func isReservedRune(r rune) bool {
+	return strings.ContainsRune("\"'();,=!<> ", r)
+}
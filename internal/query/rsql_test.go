@@ -0,0 +1,111 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCompileFilter(t *testing.T) {
+	artistID := primitive.NewObjectID()
+
+	testCases := []struct {
+		expression string
+		expected   bson.D
+	}{
+		{
+			expression: "year=ge=1500",
+			expected:   bson.D{{Key: "year", Value: bson.D{{Key: "$gte", Value: int64(1500)}}}},
+		},
+		{
+			expression: "year>1500;title!=Sunflowers",
+			expected: bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "year", Value: bson.D{{Key: "$gt", Value: int64(1500)}}}},
+				bson.D{{Key: "title", Value: bson.D{{Key: "$ne", Value: "Sunflowers"}}}},
+			}}},
+		},
+		{
+			expression: "title==*night*,(year<=1800;artist_id==" + artistID.Hex() + ")",
+			expected: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "title", Value: primitive.Regex{Pattern: "^.*night.*$", Options: "i"}}},
+				bson.D{{Key: "$and", Value: bson.A{
+					bson.D{{Key: "year", Value: bson.D{{Key: "$lte", Value: int64(1800)}}}},
+					bson.D{{Key: "artist_id", Value: artistID}},
+				}}},
+			}}},
+		},
+		{
+			expression: "year=out=(1889,1890)",
+			expected:   bson.D{{Key: "year", Value: bson.D{{Key: "$nin", Value: bson.A{int64(1889), int64(1890)}}}}},
+		},
+		{
+			expression: `title=='The Starry *';description!=*1.0+*`,
+			expected: bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "title", Value: "The Starry *"}},
+				bson.D{{Key: "description", Value: bson.D{{Key: "$not", Value: primitive.Regex{Pattern: `^.*1\.0\+.*$`, Options: "i"}}}}},
+			}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		compiled, _, err := compileFilter(tc.expression, artworkFilterable)
+		require.NoError(t, err, tc.expression)
+		require.Equal(t, tc.expected, compiled, tc.expression)
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	testCases := []struct {
+		expression      string
+		expectedMessage string
+	}{
+		{expression: "", expectedMessage: "is empty at position 1"},
+		{expression: "color==red", expectedMessage: "cannot filter on color, filterable fields are _id, artist.name, artist_id, description, title, year at position 1"},
+		{expression: "year=like=1500", expectedMessage: "expects an operator at position 5"},
+		{expression: "year==15o0", expectedMessage: "expects a number at position 7"},
+		{expression: "year==*5", expectedMessage: "can only match * on a text field at position 7"},
+		{expression: "title==", expectedMessage: "expects a value at position 8"},
+		{expression: "title=='Sun", expectedMessage: "has an unclosed quote at position 8"},
+		{expression: "(year==1500;title==Sun", expectedMessage: "has an unclosed ( at position 1"},
+		{expression: "year=in=1500", expectedMessage: "expects a ( of values at position 9"},
+		{expression: "year==1500)", expectedMessage: `has an unexpected ')' at position 11`},
+		{expression: "artist_id==1", expectedMessage: "expects an ID at position 12"},
+		{expression: "((((((((((((year==1))))))))))))", expectedMessage: "nests groups more than 10 deep at position 11"},
+	}
+
+	for _, tc := range testCases {
+		_, _, err := compileFilter(tc.expression, artworkFilterable)
+		require.Equal(t, &model.ParameterError{Parameter: "filter", Message: tc.expectedMessage}, err, tc.expression)
+	}
+}
+
+func TestFilterExpression(t *testing.T) {
+	artists, err := NewArtistQuery(map[string][]string{"filter": {"name==Monet"}})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{
+		{Key: "deleted_at", Value: nil},
+		{Key: "$and", Value: bson.A{bson.D{{Key: "name", Value: "Monet"}}}},
+	}, artists.GetFilter())
+
+	_, err = NewExhibitionQuery(map[string][]string{"filter": {"year==1900"}})
+	require.Equal(t, &model.ParameterError{Parameter: "filter", Message: "cannot filter on year, filterable fields are _id, description, name at position 1"}, err)
+
+	artworks, err := NewArtworkQuery(map[string][]string{"filter": {"year=ge=1500;artist.name==*Gogh*"}})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "deleted_at", Value: nil}}, artworks.GetCountFilter())
+
+	expression := bson.D{{Key: "$and", Value: bson.A{bson.D{{Key: "$and", Value: bson.A{
+		bson.D{{Key: "year", Value: bson.D{{Key: "$gte", Value: int64(1500)}}}},
+		bson.D{{Key: "artist.name", Value: primitive.Regex{Pattern: "^.*Gogh.*$", Options: "i"}}},
+	}}}}}}
+	pipeline := artworks.GetPipeline()
+	require.Equal(t, []bson.D{ArtworkLookupStage, ArtworkUnwindStage}, pipeline[1:3])
+	require.Equal(t, bson.D{{Key: "$match", Value: expression}}, pipeline[3])
+
+	countPipeline := artworks.GetCountPipeline()
+	require.Equal(t, pipeline[:4], countPipeline[:4])
+	require.Equal(t, CountStage, countPipeline[4])
+}
@@ -0,0 +1,113 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/iamnotrodger/art-house-api/cmd/config"
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SearchTypes are the types a search finds, in the order that results of equal
+// score are ranked
+var SearchTypes = []string{model.SearchArtwork, model.SearchArtist, model.SearchExhibition}
+
+var errSearchRequired = &model.ParameterError{Parameter: "q", Message: "is required"}
+
+type SearchQueryParams struct {
+	limit int64
+	skip  int64
+	checking
+	search string
+	types  []string
+}
+
+// NewSearchQuery parses the query parameters of a search across every type,
+// repeating type narrows the search down to some of them
+func NewSearchQuery(parameters map[string][]string) (*SearchQueryParams, error) {
+	params := NewParameters(parameters)
+	query := &SearchQueryParams{}
+	query.params = params
+
+	query.limit = params.limit("limit", searchLimits())
+	query.skip = params.skip()
+	// envelope is read by the response of the page, it is only checked here
+	params.flag("envelope")
+	query.search = strings.TrimSpace(params.Get("q"))
+	if query.search == "" {
+		return nil, errSearchRequired
+	}
+	if err := query.setTypes(params); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// GetFilter matches the documents of any type that the search finds
+func (q *SearchQueryParams) GetFilter() bson.D {
+	filter := bson.D{}
+	filter = append(filter, NotDeletedFilter...)
+	filter = append(filter, TextFilter(q.search))
+	return filter
+}
+
+// GetPipeline finds the best results of a type up to the end of the page.
+// Results of every type are ranked together, so the page is only cut out of
+// them once they are merged.
+func (q *SearchQueryParams) GetPipeline() []bson.D {
+	match := bson.D{{Key: "$match", Value: q.GetFilter()}}
+	score := bson.D{{Key: "$addFields", Value: bson.D{ScoreSort}}}
+	sort := bson.D{{Key: "$sort", Value: bson.D{{Key: ScoreKey, Value: -1}, {Key: "_id", Value: 1}}}}
+	limit := bson.D{{Key: "$limit", Value: q.skip + q.limit}}
+	return []bson.D{match, score, sort, limit}
+}
+
+// GetCountPipeline counts the documents of a type that the search finds
+func (q *SearchQueryParams) GetCountPipeline() []bson.D {
+	return CountPipeline(q.GetFilter())
+}
+
+func (q *SearchQueryParams) GetLimit() int64 {
+	return q.limit
+}
+
+func (q *SearchQueryParams) GetSkip() int64 {
+	return q.skip
+}
+
+// GetTypes returns the types to search, every type unless the search was narrowed down
+func (q *SearchQueryParams) GetTypes() []string {
+	if len(q.types) == 0 {
+		return SearchTypes
+	}
+	return q.types
+}
+
+func (q *SearchQueryParams) SetLimit(limit int64) {
+	q.limit = searchLimits().clamp(limit)
+}
+
+func (q *SearchQueryParams) SetSkip(skip int64) {
+	if skip > 0 {
+		q.skip = skip
+	}
+}
+
+// setTypes keeps the types in the order of SearchTypes
+func (q *SearchQueryParams) setTypes(params *Parameters) error {
+	types, err := params.Choices("type", SearchTypes)
+	if err != nil {
+		return err
+	}
+	q.types = types
+	return nil
+}
+
+func searchLimits() limits {
+	return limits{
+		value: config.Global.SearchLimit,
+		min:   config.Global.SearchLimitMin,
+		max:   config.Global.SearchLimitMax,
+	}
+}
@@ -0,0 +1,87 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSearch(t *testing.T) {
+	text := bson.E{Key: "$text", Value: bson.D{{Key: "$search", Value: "monet"}}}
+
+	artists, err := NewArtistQuery(map[string][]string{"search": {"monet"}})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "deleted_at", Value: nil}, text}, artists.GetFilter())
+
+	exhibitions, err := NewExhibitionQuery(map[string][]string{"search": {"monet"}, "sort": {"score:desc", "name:asc"}})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "deleted_at", Value: nil}, text}, exhibitions.GetFilter())
+	require.Equal(t, bson.D{
+		{Key: "score", Value: bson.D{{Key: "$meta", Value: "textScore"}}},
+		{Key: "name", Value: 1},
+		{Key: "_id", Value: 1},
+	}, exhibitions.GetSort())
+}
+
+func TestScoreSort(t *testing.T) {
+	_, err := NewArtistQuery(map[string][]string{"sort": {"score:desc"}})
+	require.Equal(t, &model.ParameterError{Parameter: "sort", Message: "cannot sort on score without a search"}, err)
+
+	_, err = NewArtistQuery(map[string][]string{"search": {"monet"}, "sort": {"score:asc"}})
+	require.Equal(t, &model.ParameterError{Parameter: "sort", Message: "can only sort on score:desc"}, err)
+
+	last, _ := bson.Marshal(bson.D{{Key: "_id", Value: primitive.NewObjectID()}})
+	cursor, err := encodeCursor(bson.D{{Key: "_id", Value: 1}}, last)
+	require.NoError(t, err)
+
+	_, err = NewArtworkQuery(map[string][]string{"search": {"monet"}, "sort": {"score:desc"}, "cursor": {cursor}})
+	require.Equal(t, errCursorScore, err)
+
+	query, err := NewArtworkQuery(map[string][]string{"search": {"monet"}, "sort": {"score:desc"}, "limit": {"1"}})
+	require.NoError(t, err)
+	page, err := NewPage(1, last, 5, query)
+	require.NoError(t, err)
+	require.Equal(t, "", page.NextCursor)
+}
+
+func TestSearchQuery(t *testing.T) {
+	query, err := NewSearchQuery(map[string][]string{"q": {" monet "}, "limit": {"2"}, "skip": {"4"}})
+	require.NoError(t, err)
+	require.Equal(t, SearchTypes, query.GetTypes())
+	require.Equal(t, []bson.D{
+		{{Key: "$match", Value: bson.D{
+			{Key: "deleted_at", Value: nil},
+			{Key: "$text", Value: bson.D{{Key: "$search", Value: "monet"}}},
+		}}},
+		{{Key: "$addFields", Value: bson.D{ScoreSort}}},
+		{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: int64(6)}},
+	}, query.GetPipeline())
+
+	query, err = NewSearchQuery(map[string][]string{"q": {"monet"}, "type": {"exhibition", "artwork", "artwork"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{model.SearchArtwork, model.SearchExhibition}, query.GetTypes())
+
+	_, err = NewSearchQuery(map[string][]string{"q": {" "}})
+	require.Equal(t, errSearchRequired, err)
+
+	_, err = NewSearchQuery(map[string][]string{"q": {"monet"}, "type": {"artworks"}})
+	require.Equal(t, &model.ParameterError{Parameter: "type", Message: "must be one of artwork, artist, exhibition"}, err)
+}
+
+func TestSuggestQuery(t *testing.T) {
+	query, err := NewSuggestQuery(map[string][]string{"q": {"Mon "}})
+	require.NoError(t, err)
+	require.Equal(t, "Mon", query.GetSearch())
+	require.Equal(t, int64(10), query.GetLimit())
+
+	query, err = NewSuggestQuery(map[string][]string{"q": {"Mon"}, "limit": {"500"}})
+	require.NoError(t, err)
+	require.Equal(t, int64(25), query.GetLimit())
+
+	_, err = NewSuggestQuery(map[string][]string{})
+	require.Equal(t, errSearchRequired, err)
+}
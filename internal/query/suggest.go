@@ -0,0 +1,49 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/iamnotrodger/art-house-api/cmd/config"
+)
+
+type SuggestQueryParams struct {
+	limit int64
+	checking
+	search string
+}
+
+// NewSuggestQuery parses the query parameters of the suggestions for what is
+// typed in a search
+func NewSuggestQuery(parameters map[string][]string) (*SuggestQueryParams, error) {
+	params := NewParameters(parameters)
+	query := &SuggestQueryParams{}
+	query.params = params
+
+	query.limit = params.limit("limit", suggestLimits())
+	query.search = strings.TrimSpace(params.Get("q"))
+	if query.search == "" {
+		return nil, errSearchRequired
+	}
+
+	return query, nil
+}
+
+func (q *SuggestQueryParams) GetLimit() int64 {
+	return q.limit
+}
+
+func (q *SuggestQueryParams) GetSearch() string {
+	return q.search
+}
+
+func (q *SuggestQueryParams) SetLimit(limit int64) {
+	q.limit = suggestLimits().clamp(limit)
+}
+
+func suggestLimits() limits {
+	return limits{
+		value: config.Global.SuggestLimit,
+		min:   config.Global.SuggestLimitMin,
+		max:   config.Global.SuggestLimitMax,
+	}
+}
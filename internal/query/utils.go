@@ -1,12 +1,29 @@
 package query
 
 import (
+	"reflect"
 	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
 )
 
+// ScoreKey sorts the results of a search by relevance, as in sort=score:desc
+const ScoreKey = "score"
+
 var (
+	// NotDeletedFilter excludes soft deleted documents
+	NotDeletedFilter = bson.D{{Key: "deleted_at", Value: nil}}
+	NotDeletedStage  = bson.D{{Key: "$match", Value: NotDeletedFilter}}
+	// DeletedFilter matches the documents in the trash
+	DeletedFilter = bson.D{{Key: "deleted_at", Value: bson.M{"$ne": nil}}}
+
+	// DeletedSort orders the trash, most recently deleted first
+	DeletedSort = bson.E{Key: "deleted_at", Value: -1}
+	// ScoreSort orders the results of a $text search by relevance, best first
+	ScoreSort = bson.E{Key: ScoreKey, Value: bson.D{{Key: "$meta", Value: "textScore"}}}
+	// CuratedSort orders the artworks of an exhibition by their position in it
+	CuratedSort = bson.E{Key: "position", Value: 1}
+
 	ArtworkLookupStage = bson.D{
 		{Key: "$lookup",
 			Value: bson.D{
@@ -26,10 +43,43 @@ var (
 		}}
 )
 
-func parseSort(sortString string) (string, int) {
+// JoinArtist joins the artists into the artworks of the pipeline, unless the
+// pipeline already joined them to sort or filter on them
+func JoinArtist(pipeline []bson.D) []bson.D {
+	if JoinsArtist(pipeline) {
+		return pipeline
+	}
+	return append(pipeline, ArtworkLookupStage, ArtworkUnwindStage)
+}
+
+// JoinsArtist reports whether the pipeline joins the artists into the artworks
+func JoinsArtist(pipeline []bson.D) bool {
+	for _, stage := range pipeline {
+		if reflect.DeepEqual(stage, ArtworkLookupStage) {
+			return true
+		}
+	}
+	return false
+}
+
+// TextFilter matches the documents of a $text search
+func TextFilter(search string) bson.E {
+	return bson.E{Key: "$text", Value: bson.D{{Key: "$search", Value: search}}}
+}
+
+// VersionFilter matches documents stored at the version, documents stored
+// before versioning have no version field and are at version 0
+func VersionFilter(version int64) bson.E {
+	if version == 0 {
+		return bson.E{Key: "version", Value: nil}
+	}
+	return bson.E{Key: "version", Value: version}
+}
+
+func parseSort(sortString string) (string, int, error) {
 	pair := strings.Split(sortString, ":")
-	if len(pair) != 2 {
-		return "", 0
+	if len(pair) != 2 || pair[0] == "" {
+		return "", 0, errInvalidSort
 	}
 
 	key := pair[0]
@@ -41,16 +91,17 @@ func parseSort(sortString string) (string, int) {
 	} else if order == "desc" {
 		value = -1
 	} else {
-		return "", 0
+		return "", 0, errInvalidSort
 	}
 
-	return key, value
+	return key, value, nil
 }
 
-func getSortAsBson(sortMap map[string]int) bson.D {
-	sort := bson.D{}
-	for key, value := range sortMap {
-		sort = append(sort, bson.E{Key: key, Value: value})
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
-	return sort
+	return false
 }
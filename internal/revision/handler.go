@@ -0,0 +1,175 @@
+package revision
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AuthorHeader is the request header that names the author of a write
+const AuthorHeader = "X-Author"
+
+// Syncer brings what depends on a document back in line with it after it is
+// reverted: the documents that copy its fields and the cached entries that hold it
+type Syncer interface {
+	SyncReverted(ctx context.Context, id string) error
+}
+
+// Validator checks that a snapshot can be stored as the document before it is
+// reverted to, as the document is checked before any other write
+type Validator interface {
+	ValidateSnapshot(ctx context.Context, snapshot bson.M) error
+}
+
+type Handler struct {
+	store      *Store
+	collection string
+	syncer     Syncer
+	validator  Validator
+}
+
+func NewHandler(store *Store, collection string, syncer Syncer, validator Validator) *Handler {
+	return &Handler{
+		store:      store,
+		collection: collection,
+		syncer:     syncer,
+		validator:  validator,
+	}
+}
+
+// RegisterRoutes registers the history routes of the documents served under path
+func (h *Handler) RegisterRoutes(router *mux.Router, path string) {
+	router.HandleFunc(path+"/{id}/history", h.GetMany).Methods("GET")
+	router.HandleFunc(path+"/{id}/history/diff", h.GetDiff).Methods("GET")
+	router.HandleFunc(path+"/{id}/history/{number}", h.Get).Methods("GET")
+	router.HandleFunc(path+"/{id}/history/{number}/revert", h.Revert).Methods("POST")
+}
+
+func (h *Handler) GetMany(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	documentID := params["id"]
+
+	revisions, err := h.store.FindMany(r.Context(), h.collection, documentID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	util.RespondWithJSON(w, r, http.StatusOK, revisions)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	documentID := params["id"]
+	number, err := strconv.ParseInt(params["number"], 10, 64)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid revision number")
+		return
+	}
+
+	revision, err := h.store.Find(r.Context(), h.collection, documentID, number)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	util.RespondWithJSON(w, r, http.StatusOK, revision)
+}
+
+// GetDiff compares the revisions given by the from and to query parameters
+func (h *Handler) GetDiff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	documentID := params["id"]
+
+	from, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		util.HandleError(w, &model.ParameterError{Parameter: "from", Message: "must be a number"})
+		return
+	}
+	to, err := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if err != nil {
+		util.HandleError(w, &model.ParameterError{Parameter: "to", Message: "must be a number"})
+		return
+	}
+
+	fromRevision, err := h.store.Find(r.Context(), h.collection, documentID, from)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	toRevision, err := h.store.Find(r.Context(), h.collection, documentID, to)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	util.RespondWithJSON(w, r, http.StatusOK, model.DiffRevisions(fromRevision, toRevision))
+}
+
+// Revert restores the document to a revision and records the result as a new revision
+func (h *Handler) Revert(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := mux.Vars(r)
+	documentID := params["id"]
+	number, err := strconv.ParseInt(params["number"], 10, 64)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid revision number")
+		return
+	}
+	version, err := util.GetIfMatch(r, func() (int64, error) {
+		return h.store.DocumentVersion(r.Context(), h.collection, documentID)
+	})
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	document, err := h.store.Revert(r.Context(), h.collection, documentID, number, version, h.validator)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	err = h.syncer.SyncReverted(r.Context(), documentID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	revision, err := h.store.Record(r.Context(), h.collection, model.RevisionRevert, GetAuthor(r), document)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	// the ETag carries the document's new version so that it can be written
+	// again, which the sync moves on when it rewrites fields of the document
+	version, err = h.store.DocumentVersion(r.Context(), h.collection, documentID)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	res, err := util.NewVersionedResponse(revision, version)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	res.Write(w, r, http.StatusOK)
+}
+
+func GetAuthor(r *http.Request) string {
+	return r.Header.Get(AuthorHeader)
+}
@@ -0,0 +1,187 @@
+package revision
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/query"
+	"github.com/iamnotrodger/art-house-api/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Store struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewStore(db *mongo.Database) *Store {
+	return &Store{
+		db:         db,
+		collection: db.Collection("revisions"),
+	}
+}
+
+// Record saves the snapshot of a document as a write left it stored, numbered
+// by the version that the write left it at. A purged document is recorded
+// without a snapshot, numbered after the version it was purged at.
+func (s *Store) Record(ctx context.Context, collection string, action string, author string, document bson.M) (*model.Revision, error) {
+	id, ok := document["_id"].(primitive.ObjectID)
+	if !ok {
+		return nil, model.ErrInvalidID
+	}
+
+	number := documentVersion(document)
+	snapshot := document
+	if action == model.RevisionPurge {
+		number++
+		snapshot = nil
+	}
+
+	revision := &model.Revision{
+		ID:         primitive.NewObjectID(),
+		Collection: collection,
+		DocumentID: id,
+		Number:     number,
+		Action:     action,
+		Author:     author,
+		CreatedAt:  time.Now(),
+		Snapshot:   snapshot,
+	}
+	_, err := s.collection.InsertOne(ctx, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// CreateIndexes creates the index that keeps each revision number of a document
+// unique, so that two writes cannot record the same revision
+func (s *Store) CreateIndexes(ctx context.Context) error {
+	index := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "collection", Value: 1},
+			{Key: "document_id", Value: 1},
+			{Key: "number", Value: 1},
+		},
+		Options: options.Index().SetName("revisions_number").SetUnique(true),
+	}
+	_, err := s.collection.Indexes().CreateOne(ctx, index)
+	return err
+}
+
+// FindMany returns the revisions of the document, most recent first
+func (s *Store) FindMany(ctx context.Context, collection string, documentID string) ([]*model.Revision, error) {
+	id, err := primitive.ObjectIDFromHex(documentID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	filter := bson.M{"collection": collection, "document_id": id}
+	opts := options.Find().SetSort(bson.D{{Key: "number", Value: -1}})
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []*model.Revision{}
+	err = cursor.All(ctx, &revisions)
+	if err != nil {
+		err = fmt.Errorf("failed to unmarshal revisions: %w", err)
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (s *Store) Find(ctx context.Context, collection string, documentID string, number int64) (*model.Revision, error) {
+	id, err := primitive.ObjectIDFromHex(documentID)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	filter := bson.M{"collection": collection, "document_id": id, "number": number}
+	singleRes := s.collection.FindOne(ctx, filter)
+	if err = singleRes.Err(); err == mongo.ErrNoDocuments {
+		return nil, model.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	revision := &model.Revision{}
+	err = singleRes.Decode(revision)
+	if err != nil {
+		err = fmt.Errorf("error decoding revision: %w", err)
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// DocumentVersion returns the version the document of the collection is stored at
+func (s *Store) DocumentVersion(ctx context.Context, collection string, documentID string) (int64, error) {
+	return util.FindVersion(ctx, s.db.Collection(collection), documentID)
+}
+
+// Revert replaces the document with the snapshot of one of its revisions if it is
+// still stored at version, and returns the document as it was stored. The
+// snapshot must pass the validator as a write would, and documents in the trash
+// cannot be reverted.
+func (s *Store) Revert(ctx context.Context, collection string, documentID string, number int64, version int64, validator Validator) (bson.M, error) {
+	revision, err := s.Find(ctx, collection, documentID, number)
+	if err != nil {
+		return nil, err
+	}
+	if revision.Snapshot == nil {
+		return nil, &model.ValidationError{Field: "revision", Message: "has no snapshot to revert to"}
+	}
+
+	replacement := bson.M{}
+	for key, value := range revision.Snapshot {
+		if key != "deleted_at" {
+			replacement[key] = value
+		}
+	}
+	replacement["version"] = version + 1
+
+	err = validator.ValidateSnapshot(ctx, replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := s.db.Collection(collection)
+	filter := bson.D{{Key: "_id", Value: revision.DocumentID}, {Key: "deleted_at", Value: nil}, query.VersionFilter(version)}
+	document, err := util.ReplaceAndReturn(ctx, documents, filter, replacement)
+	if err == mongo.ErrNoDocuments {
+		count, err := documents.CountDocuments(ctx, bson.M{"_id": revision.DocumentID, "deleted_at": nil})
+		if err != nil {
+			return nil, err
+		}
+		if count < 1 {
+			return nil, model.ErrNotFound
+		}
+		return nil, &model.PreconditionError{Message: "document has been modified"}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// documentVersion returns the version of a stored document, documents stored
+// before versioning are at version 0
+func documentVersion(document bson.M) int64 {
+	switch version := document["version"].(type) {
+	case int64:
+		return version
+	case int32:
+		return int64(version)
+	}
+	return 0
+}
@@ -0,0 +1,229 @@
+package revision
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var (
+	documentID          = "60e0850266d6c13d7b599b69"
+	documentObjectID, _ = primitive.ObjectIDFromHex(documentID)
+)
+
+// snapshotValidator rejects every snapshot with its error
+type snapshotValidator struct {
+	err error
+}
+
+func (v snapshotValidator) ValidateSnapshot(ctx context.Context, snapshot bson.M) error {
+	return v.err
+}
+
+func TestRecord(t *testing.T) {
+	testCases := []struct {
+		name             string
+		action           string
+		document         bson.M
+		dbResponse       []bson.D
+		expectedNumber   int64
+		expectedSnapshot bson.M
+		expectedError    error
+	}{
+		{
+			name:          "document without ID",
+			action:        model.RevisionUpdate,
+			document:      bson.M{"title": "title"},
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:     "numbered by the version",
+			action:   model.RevisionUpdate,
+			document: bson.M{"_id": documentObjectID, "title": "title", "version": int64(3)},
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(),
+			},
+			expectedNumber:   3,
+			expectedSnapshot: bson.M{"_id": documentObjectID, "title": "title", "version": int64(3)},
+			expectedError:    nil,
+		},
+		{
+			name:     "purged document has no snapshot",
+			action:   model.RevisionPurge,
+			document: bson.M{"_id": documentObjectID, "title": "title", "version": int32(3)},
+			dbResponse: []bson.D{
+				mtest.CreateSuccessResponse(),
+			},
+			expectedNumber:   4,
+			expectedSnapshot: nil,
+			expectedError:    nil,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			revision, err := store.Record(context.Background(), "artworks", tc.action, "curator", tc.document)
+			require.Equal(mt, tc.expectedError, err)
+			if tc.expectedError == nil {
+				require.Equal(mt, tc.expectedNumber, revision.Number)
+				require.Equal(mt, tc.expectedSnapshot, revision.Snapshot)
+				require.Equal(mt, "curator", revision.Author)
+			}
+		})
+	}
+}
+
+func TestFindMany(t *testing.T) {
+	testCases := []struct {
+		name          string
+		documentID    string
+		dbResponse    []bson.D
+		expectedCount int
+		expectedError error
+	}{
+		{
+			name:          "invalid documentID",
+			documentID:    "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:       "revisions found",
+			documentID: documentID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.revisions", mtest.FirstBatch,
+					bson.D{{Key: "document_id", Value: documentObjectID}, {Key: "number", Value: 2}},
+					bson.D{{Key: "document_id", Value: documentObjectID}, {Key: "number", Value: 1}},
+				),
+			},
+			expectedCount: 2,
+			expectedError: nil,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			revisions, err := store.FindMany(context.Background(), "artworks", tc.documentID)
+			require.Equal(mt, tc.expectedError, err)
+			require.Equal(mt, tc.expectedCount, len(revisions))
+		})
+	}
+}
+
+func TestRevert(t *testing.T) {
+	testCases := []struct {
+		name          string
+		documentID    string
+		dbResponse    []bson.D
+		validator     snapshotValidator
+		expectedError error
+	}{
+		{
+			name:          "invalid documentID",
+			documentID:    "invalid_ID",
+			dbResponse:    []bson.D{},
+			expectedError: model.ErrInvalidID,
+		},
+		{
+			name:       "document reverted",
+			documentID: documentID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.revisions", mtest.FirstBatch, bson.D{
+					{Key: "document_id", Value: documentObjectID},
+					{Key: "number", Value: 1},
+					{Key: "snapshot", Value: bson.D{{Key: "_id", Value: documentObjectID}, {Key: "title", Value: "title"}}},
+				}),
+				mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{{Key: "_id", Value: documentObjectID}, {Key: "version", Value: int64(3)}}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "invalid snapshot rejected",
+			documentID: documentID,
+			validator:  snapshotValidator{err: &model.ValidationError{Field: "title", Message: "is required"}},
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.revisions", mtest.FirstBatch, bson.D{
+					{Key: "document_id", Value: documentObjectID},
+					{Key: "number", Value: 1},
+					{Key: "snapshot", Value: bson.D{{Key: "_id", Value: documentObjectID}}},
+				}),
+			},
+			expectedError: &model.ValidationError{Field: "title", Message: "is required"},
+		},
+		{
+			name:       "revision not found",
+			documentID: documentID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.revisions", mtest.FirstBatch),
+			},
+			expectedError: model.ErrNotFound,
+		},
+		{
+			name:       "revision without snapshot",
+			documentID: documentID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.revisions", mtest.FirstBatch, bson.D{
+					{Key: "document_id", Value: documentObjectID},
+					{Key: "number", Value: 1},
+				}),
+			},
+			expectedError: &model.ValidationError{Field: "revision", Message: "has no snapshot to revert to"},
+		},
+		{
+			name:       "document not found or in trash",
+			documentID: documentID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.revisions", mtest.FirstBatch, bson.D{
+					{Key: "document_id", Value: documentObjectID},
+					{Key: "number", Value: 1},
+					{Key: "snapshot", Value: bson.D{{Key: "_id", Value: documentObjectID}}},
+				}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
+			},
+			expectedError: model.ErrNotFound,
+		},
+		{
+			name:       "document modified since version",
+			documentID: documentID,
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.revisions", mtest.FirstBatch, bson.D{
+					{Key: "document_id", Value: documentObjectID},
+					{Key: "number", Value: 1},
+					{Key: "snapshot", Value: bson.D{{Key: "_id", Value: documentObjectID}}},
+				}),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			},
+			expectedError: &model.PreconditionError{Message: "document has been modified"},
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			_, err := store.Revert(context.Background(), "artworks", tc.documentID, 1, 2, tc.validator)
+			require.Equal(mt, tc.expectedError, err)
+		})
+	}
+}
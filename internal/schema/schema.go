@@ -0,0 +1,162 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	timeType     = reflect.TypeOf(time.Time{})
+
+	objectIDSchema      = bson.M{"bsonType": "objectId"}
+	objectIDArraySchema = bson.M{"bsonType": bson.A{"array", "null"}, "items": objectIDSchema}
+)
+
+// Collection describes how a model is stored in its collection
+type Collection struct {
+	Name     string
+	Model    interface{}
+	Required []string
+	// Stored replaces the schema of the model's fields that are stored differently than
+	// they are read, such as the references that are joined in with a lookup. A nil
+	// schema removes the field.
+	Stored map[string]bson.M
+	// TextIndex lists the fields of the collection's $text index
+	TextIndex bson.D
+	// Indexes lists the keys of the collection's other indexes
+	Indexes []bson.D
+}
+
+var Collections = []*Collection{
+	{
+		Name:     "artworks",
+		Model:    model.Artwork{},
+		Required: []string{"_id", "title", "artist_id"},
+		Stored: map[string]bson.M{
+			"artist":    nil,
+			"artist_id": objectIDSchema,
+		},
+		TextIndex: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}},
+	},
+	{
+		Name:      "artists",
+		Model:     model.Artist{},
+		Required:  []string{"_id", "name"},
+		TextIndex: bson.D{{Key: "name", Value: "text"}},
+	},
+	{
+		Name:     "exhibitions",
+		Model:    model.Exhibition{},
+		Required: []string{"_id", "name"},
+		Stored: map[string]bson.M{
+			"artists":     nil,
+			"artworks":    nil,
+			"artist_ids":  objectIDArraySchema,
+			"artwork_ids": objectIDArraySchema,
+		},
+		TextIndex: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+		// the artworks are joined to the exhibitions that show them by artwork_ids
+		Indexes: []bson.D{{{Key: "artwork_ids", Value: 1}}},
+	},
+}
+
+// Schema returns the $jsonSchema that the collection's documents must match
+func (c *Collection) Schema() bson.M {
+	schema := typeSchema(reflect.TypeOf(c.Model))
+
+	properties := schema["properties"].(bson.M)
+	for field, fieldSchema := range c.Stored {
+		if fieldSchema == nil {
+			delete(properties, field)
+		} else {
+			properties[field] = fieldSchema
+		}
+	}
+	schema["required"] = c.Required
+
+	return schema
+}
+
+// Validator returns the validator of the collection
+func (c *Collection) Validator() bson.M {
+	return bson.M{"$jsonSchema": c.Schema()}
+}
+
+// typeSchema returns the schema of the values of a Go type as they are encoded by
+// the bson package, a nil pointer or slice is encoded as null
+func typeSchema(t reflect.Type) bson.M {
+	switch t {
+	case objectIDType:
+		return bson.M{"bsonType": "objectId"}
+	case timeType:
+		return bson.M{"bsonType": "date"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(typeSchema(t.Elem()))
+	case reflect.Slice, reflect.Array:
+		return bson.M{"bsonType": bson.A{"array", "null"}, "items": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	case reflect.String:
+		return bson.M{"bsonType": "string"}
+	case reflect.Bool:
+		return bson.M{"bsonType": "bool"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return bson.M{"bsonType": bson.A{"int", "long"}}
+	case reflect.Float32, reflect.Float64:
+		return bson.M{"bsonType": "number"}
+	default:
+		return bson.M{}
+	}
+}
+
+func nullable(schema bson.M) bson.M {
+	switch bsonType := schema["bsonType"].(type) {
+	case string:
+		schema["bsonType"] = bson.A{bsonType, "null"}
+	case bson.A:
+		schema["bsonType"] = append(bsonType, "null")
+	}
+	return schema
+}
+
+func structSchema(t reflect.Type) bson.M {
+	properties := bson.M{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		properties[name] = typeSchema(field.Type)
+	}
+
+	return bson.M{
+		"bsonType":             "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// fieldName returns the key of the field in its bson document
+func fieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("bson")
+	name := strings.TrimSpace(strings.Split(tag, ",")[0])
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var (
+	MongoFailResponse    = bson.D{{Key: "ok", Value: 0}}
+	MongoFailRaw, _      = bson.Marshal(MongoFailResponse)
+	ErrMongoCommandError = mongo.CommandError{Message: "command failed", Raw: MongoFailRaw}
+)
+
+func TestSchema(t *testing.T) {
+	artworks := Collections[0]
+	schema := artworks.Schema()
+
+	require.Equal(t, "object", schema["bsonType"])
+	require.Equal(t, false, schema["additionalProperties"])
+	require.Equal(t, []string{"_id", "title", "artist_id"}, schema["required"])
+
+	properties := schema["properties"].(bson.M)
+	require.NotContains(t, properties, "artist")
+	require.Equal(t, bson.M{"bsonType": "objectId"}, properties["artist_id"])
+	require.Equal(t, bson.M{"bsonType": "objectId"}, properties["_id"])
+	require.Equal(t, bson.M{"bsonType": "string"}, properties["title"])
+	require.Equal(t, bson.M{"bsonType": bson.A{"int", "long"}}, properties["year"])
+	require.Equal(t, bson.M{"bsonType": bson.A{"date", "null"}}, properties["deleted_at"])
+
+	images := properties["images"].(bson.M)
+	require.Equal(t, bson.A{"array", "null"}, images["bsonType"])
+	image := images["items"].(bson.M)
+	require.Equal(t, bson.A{"object", "null"}, image["bsonType"])
+	require.Equal(t, bson.M{
+		"height": bson.M{"bsonType": bson.A{"number", "null"}},
+		"width":  bson.M{"bsonType": bson.A{"number", "null"}},
+		"url":    bson.M{"bsonType": "string"},
+	}, image["properties"])
+}
+
+func TestSync(t *testing.T) {
+	testCases := []struct {
+		name            string
+		collection      *Collection
+		dbResponse      []bson.D
+		expectedCreated bool
+		expectedError   error
+	}{
+		{
+			name: "collection created",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.$cmd.listCollections", mtest.FirstBatch),
+				mtest.CreateSuccessResponse(),
+				mtest.CreateSuccessResponse(),
+			},
+			expectedCreated: true,
+			expectedError:   nil,
+		},
+		{
+			name:       "validator updated and text index created",
+			collection: Collections[1],
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.$cmd.listCollections", mtest.FirstBatch, bson.D{
+					{Key: "name", Value: "artists"},
+					{Key: "type", Value: "collection"},
+				}),
+				mtest.CreateSuccessResponse(),
+				mtest.CreateSuccessResponse(),
+			},
+			expectedCreated: false,
+			expectedError:   nil,
+		},
+		{
+			name:       "text index fails with an error",
+			collection: Collections[2],
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.$cmd.listCollections", mtest.FirstBatch),
+				mtest.CreateSuccessResponse(),
+				MongoFailResponse,
+			},
+			expectedCreated: true,
+			expectedError:   ErrMongoCommandError,
+		},
+		{
+			name: "update fails with an error",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.$cmd.listCollections", mtest.FirstBatch, bson.D{
+					{Key: "name", Value: "artworks"},
+					{Key: "type", Value: "collection"},
+				}),
+				MongoFailResponse,
+			},
+			expectedCreated: false,
+			expectedError:   ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			collection := tc.collection
+			if collection == nil {
+				collection = Collections[0]
+			}
+
+			created, err := Sync(context.Background(), mt.DB, collection, "moderate", "error")
+			require.Equal(mt, tc.expectedError, err)
+			require.Equal(mt, tc.expectedCreated, created)
+		})
+	}
+}
+
+func TestSyncIndexes(t *testing.T) {
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	m.Run("text and other indexes created", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "art-house.$cmd.listCollections", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+		)
+
+		_, err := Sync(context.Background(), mt.DB, Collections[2], "moderate", "error")
+		require.NoError(mt, err)
+
+		mt.GetStartedEvent()
+		mt.GetStartedEvent()
+		event := mt.GetStartedEvent()
+		require.Equal(mt, "createIndexes", event.CommandName)
+		indexes, err := event.Command.Lookup("indexes").Array().Values()
+		require.NoError(mt, err)
+		require.Len(mt, indexes, 2)
+		require.Equal(mt, "exhibitions_text", indexes[0].Document().Lookup("name").StringValue())
+		require.Equal(mt, int32(1), indexes[1].Document().Lookup("key", "artwork_ids").Int32())
+	})
+}
+
+func TestFindViolations(t *testing.T) {
+	violationID := primitive.NewObjectID()
+
+	testCases := []struct {
+		name          string
+		dbResponse    []bson.D
+		expectedCount int64
+		expectedIDs   []interface{}
+		expectedError error
+	}{
+		{
+			name: "no violations",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
+			},
+			expectedCount: 0,
+			expectedIDs:   []interface{}{},
+			expectedError: nil,
+		},
+		{
+			name: "violations found",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "n", Value: 2}}),
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch, bson.D{{Key: "_id", Value: violationID}}),
+			},
+			expectedCount: 2,
+			expectedIDs:   []interface{}{violationID},
+			expectedError: nil,
+		},
+		{
+			name: "count fails with an error",
+			dbResponse: []bson.D{
+				MongoFailResponse,
+			},
+			expectedCount: 0,
+			expectedIDs:   nil,
+			expectedError: ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			count, ids, err := FindViolations(context.Background(), mt.DB, Collections[0], 1)
+			require.Equal(mt, tc.expectedError, err)
+			require.Equal(mt, tc.expectedCount, count)
+			require.Equal(mt, tc.expectedIDs, ids)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Sync creates the collection with its validator, or replaces the validator of the
+// collection when it already exists, then creates its indexes. It reports whether
+// the collection was created.
+func Sync(ctx context.Context, db *mongo.Database, collection *Collection, level string, action string) (bool, error) {
+	created, err := syncValidator(ctx, db, collection, level, action)
+	if err != nil {
+		return false, err
+	}
+
+	return created, syncIndexes(ctx, db, collection)
+}
+
+func syncValidator(ctx context.Context, db *mongo.Database, collection *Collection, level string, action string) (bool, error) {
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": collection.Name})
+	if err != nil {
+		return false, err
+	}
+
+	if len(names) == 0 {
+		opts := options.CreateCollection().
+			SetValidator(collection.Validator()).
+			SetValidationLevel(level).
+			SetValidationAction(action)
+		return true, db.CreateCollection(ctx, collection.Name, opts)
+	}
+
+	command := bson.D{
+		{Key: "collMod", Value: collection.Name},
+		{Key: "validator", Value: collection.Validator()},
+		{Key: "validationLevel", Value: level},
+		{Key: "validationAction", Value: action},
+	}
+	return false, db.RunCommand(ctx, command).Err()
+}
+
+// syncIndexes creates the text index and the other indexes of the collection,
+// creating an index that already exists does nothing
+func syncIndexes(ctx context.Context, db *mongo.Database, collection *Collection) error {
+	indexes := []mongo.IndexModel{}
+	if len(collection.TextIndex) > 0 {
+		indexes = append(indexes, mongo.IndexModel{
+			Keys:    collection.TextIndex,
+			Options: options.Index().SetName(collection.Name + "_text"),
+		})
+	}
+	for _, keys := range collection.Indexes {
+		indexes = append(indexes, mongo.IndexModel{Keys: keys})
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	_, err := db.Collection(collection.Name).Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// FindViolations returns the number of documents in the collection that do not
+// match its schema, along with the IDs of up to limit of them
+func FindViolations(ctx context.Context, db *mongo.Database, collection *Collection, limit int64) (int64, []interface{}, error) {
+	documents := db.Collection(collection.Name)
+	filter := bson.M{"$nor": bson.A{collection.Validator()}}
+
+	count, err := documents.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, nil, err
+	}
+	if count == 0 {
+		return 0, []interface{}{}, nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(limit)
+	cursor, err := documents.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var violations []bson.M
+	err = cursor.All(ctx, &violations)
+	if err != nil {
+		err = fmt.Errorf("failed to unmarshal %s: %w", collection.Name, err)
+		return 0, nil, err
+	}
+
+	ids := []interface{}{}
+	for _, violation := range violations {
+		ids = append(ids, violation["_id"])
+	}
+
+	return count, ids, nil
+}
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/iamnotrodger/art-house-api/internal/util"
+)
+
+// Cache holds the responses of searches. Searches span every type, so they are
+// not invalidated by writes and only expire.
+type Cache struct {
+	client     *redis.Client
+	expiration time.Duration
+	namespace  string
+}
+
+func NewCache(client *redis.Client, expiration time.Duration) *Cache {
+	return &Cache{
+		client:     client,
+		expiration: expiration,
+		namespace:  "search",
+	}
+}
+
+func (c *Cache) Get(ctx context.Context, queryString string) (*util.Response, error) {
+	return c.get(ctx, c.getKeyByQuery(queryString))
+}
+
+func (c *Cache) Set(ctx context.Context, queryString string, res *util.Response) error {
+	return c.set(ctx, c.getKeyByQuery(queryString), res)
+}
+
+func (c *Cache) get(ctx context.Context, key string) (*util.Response, error) {
+	val, err := c.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var res util.Response
+	err = json.Unmarshal([]byte(val), &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// set caches the response body along with its ETag
+func (c *Cache) set(ctx context.Context, key string, res *util.Response) error {
+	resJson, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	return c.client.Set(ctx, key, resJson, c.expiration).Err()
+}
+
+func (c *Cache) getKeyByQuery(queryString string) string {
+	return fmt.Sprintf("%s?%s", c.namespace, queryString)
+}
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/iamnotrodger/art-house-api/internal/query"
+	"github.com/iamnotrodger/art-house-api/internal/util"
+)
+
+type Handler struct {
+	store *Store
+	cache *Cache
+}
+
+func NewHandler(store *Store, cache *Cache) *Handler {
+	return &Handler{
+		store: store,
+		cache: cache,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/search", h.Search).Methods("GET")
+}
+
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	queryParams, err := query.NewSearchQuery(r.URL.Query())
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, queryParams); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	queryString := r.URL.RawQuery
+	res, err := h.cache.Get(r.Context(), queryString)
+	if err != nil {
+		log.Println(err)
+	} else if res != nil {
+		res.Write(w, r, http.StatusOK)
+		return
+	}
+
+	search, page, err := h.store.Search(r.Context(), queryParams)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	res, err = util.NewPageResponse(r, search, page)
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	err = h.cache.Set(r.Context(), queryString, res)
+	if err != nil {
+		log.Println(err)
+	}
+
+	res.Write(w, r, http.StatusOK)
+}
@@ -0,0 +1,160 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/query"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// collections maps each type a search finds to where it is stored
+var collections = map[string]string{
+	model.SearchArtwork:    "artworks",
+	model.SearchArtist:     "artists",
+	model.SearchExhibition: "exhibitions",
+}
+
+type Store struct {
+	db *mongo.Database
+}
+
+func NewStore(db *mongo.Database) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Search finds the documents of every type of the query in parallel and ranks
+// them together by their relevance score
+func (s *Store) Search(ctx context.Context, queryParam *query.SearchQueryParams) (*model.Search, *model.Page, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	types := queryParam.GetTypes()
+	found := make([][]*model.SearchResult, len(types))
+	totals := make([]int64, len(types))
+
+	// the first type to fail cancels the others, its error is the one returned
+	var wg sync.WaitGroup
+	var failed sync.Once
+	var err error
+	for i, searchType := range types {
+		wg.Add(1)
+		go func(i int, searchType string) {
+			defer wg.Done()
+			var findErr error
+			found[i], totals[i], findErr = s.find(ctx, searchType, queryParam)
+			if findErr != nil {
+				failed.Do(func() {
+					err = findErr
+					cancel()
+				})
+			}
+		}(i, searchType)
+	}
+	wg.Wait()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	search := &model.Search{Counts: map[string]int64{}}
+	page := &model.Page{Limit: queryParam.GetLimit(), Skip: queryParam.GetSkip()}
+	for i, searchType := range types {
+		search.Counts[searchType] = totals[i]
+		page.Total += totals[i]
+	}
+	search.Results = rank(found, page.Skip, page.Limit)
+
+	return search, page, nil
+}
+
+// find returns the best results of a type up to the end of the page and how
+// many documents of the type the search found
+func (s *Store) find(ctx context.Context, searchType string, queryParam *query.SearchQueryParams) ([]*model.SearchResult, int64, error) {
+	collection := s.db.Collection(collections[searchType])
+
+	pipeline := queryParam.GetPipeline()
+	if searchType == model.SearchArtwork {
+		pipeline = query.JoinArtist(pipeline)
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []*model.SearchResult{}
+	for cursor.Next(ctx) {
+		result, err := decodeResult(searchType, cursor)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, result)
+	}
+	if err = cursor.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := query.Count(ctx, collection, queryParam.GetCountPipeline())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+func decodeResult(searchType string, cursor *mongo.Cursor) (*model.SearchResult, error) {
+	result := &model.SearchResult{Type: searchType}
+	result.Score, _ = cursor.Current.Lookup(query.ScoreKey).DoubleOK()
+
+	var err error
+	switch searchType {
+	case model.SearchArtwork:
+		result.Artwork = &model.Artwork{}
+		if err = cursor.Decode(result.Artwork); err == nil {
+			model.SortImages(result.Artwork.Images)
+			model.SortImages(result.Artwork.Artist.Images)
+		}
+	case model.SearchArtist:
+		result.Artist = &model.Artist{}
+		if err = cursor.Decode(result.Artist); err == nil {
+			model.SortImages(result.Artist.Images)
+		}
+	case model.SearchExhibition:
+		result.Exhibition = &model.Exhibition{}
+		if err = cursor.Decode(result.Exhibition); err == nil {
+			model.SortImages(result.Exhibition.Images)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", searchType, err)
+	}
+
+	return result, nil
+}
+
+// rank merges the results of every type, best score first, and cuts the page
+// out of them. Results of equal score keep the order of their types.
+func rank(found [][]*model.SearchResult, skip int64, limit int64) []*model.SearchResult {
+	results := []*model.SearchResult{}
+	for _, typeResults := range found {
+		results = append(results, typeResults...)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if skip >= int64(len(results)) {
+		return []*model.SearchResult{}
+	}
+	end := skip + limit
+	if end > int64(len(results)) {
+		end = int64(len(results))
+	}
+	return results[skip:end]
+}
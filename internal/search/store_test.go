@@ -0,0 +1,95 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/query"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var (
+	MongoFailResponse    = bson.D{{Key: "ok", Value: 0}}
+	MongoFailRaw, _      = bson.Marshal(MongoFailResponse)
+	ErrMongoCommandError = mongo.CommandError{Message: "command failed", Raw: MongoFailRaw}
+
+	artistOne = primitive.NewObjectID()
+	artistTwo = primitive.NewObjectID()
+)
+
+func TestSearch(t *testing.T) {
+	testCases := []struct {
+		name           string
+		dbResponse     []bson.D
+		expectedSearch *model.Search
+		expectedPage   *model.Page
+		expectedError  error
+	}{
+		{
+			name: "artists found",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: artistOne}, {Key: "name", Value: "Claude Monet"}, {Key: "score", Value: 1.5}},
+					bson.D{{Key: "_id", Value: artistTwo}, {Key: "name", Value: "Monet"}, {Key: "score", Value: 1.0}},
+				),
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch, bson.D{{Key: "total", Value: 3}}),
+			},
+			expectedSearch: &model.Search{
+				Results: []*model.SearchResult{
+					{Type: model.SearchArtist, Score: 1.0, Artist: &model.Artist{ID: artistTwo, Name: "Monet"}},
+				},
+				Counts: map[string]int64{model.SearchArtist: 3},
+			},
+			expectedPage:  &model.Page{Total: 3, Limit: 1, Skip: 1},
+			expectedError: nil,
+		},
+		{
+			name: "count fails with an error",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch),
+				MongoFailResponse,
+			},
+			expectedSearch: nil,
+			expectedPage:   nil,
+			expectedError:  ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+			store := NewStore(mt.DB)
+
+			queryParams, err := query.NewSearchQuery(map[string][]string{
+				"q":     {"monet"},
+				"type":  {model.SearchArtist},
+				"limit": {"1"},
+				"skip":  {"1"},
+			})
+			require.NoError(mt, err)
+
+			search, page, err := store.Search(context.Background(), queryParams)
+			require.Equal(mt, tc.expectedError, err)
+			require.Equal(mt, tc.expectedSearch, search)
+			require.Equal(mt, tc.expectedPage, page)
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	artwork := &model.SearchResult{Type: model.SearchArtwork, Score: 1.0}
+	artist := &model.SearchResult{Type: model.SearchArtist, Score: 2.0}
+	exhibition := &model.SearchResult{Type: model.SearchExhibition, Score: 1.0}
+	found := [][]*model.SearchResult{{artwork}, {artist}, {exhibition}}
+
+	require.Equal(t, []*model.SearchResult{artist, artwork, exhibition}, rank(found, 0, 10))
+	require.Equal(t, []*model.SearchResult{artwork}, rank(found, 1, 1))
+	require.Equal(t, []*model.SearchResult{}, rank(found, 3, 10))
+}
@@ -0,0 +1,76 @@
+package suggest
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/iamnotrodger/art-house-api/internal/query"
+	"github.com/iamnotrodger/art-house-api/internal/util"
+)
+
+type Handler struct {
+	store *Store
+	index *Index
+}
+
+func NewHandler(store *Store, index *Index) *Handler {
+	return &Handler{
+		store: store,
+		index: index,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/suggest", h.Suggest).Methods("GET")
+}
+
+func (h *Handler) Suggest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	queryParams, err := query.NewSuggestQuery(r.URL.Query())
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	if err = util.CheckParameters(r, queryParams); err != nil {
+		util.HandleError(w, err)
+		return
+	}
+	suggestions, err := h.index.Suggest(r.Context(), queryParams.GetSearch(), queryParams.GetLimit())
+	if err != nil {
+		util.HandleError(w, err)
+		return
+	}
+
+	util.RespondWithJSON(w, r, http.StatusOK, suggestions)
+}
+
+// Refresh rebuilds the index of suggestions from the stored documents now and
+// then every interval until ctx is done
+func (h *Handler) Refresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.rebuild(ctx); err != nil {
+			log.Println(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) rebuild(ctx context.Context) error {
+	suggestions, err := h.store.FindSuggestions(ctx)
+	if err != nil {
+		return err
+	}
+	return h.index.Rebuild(ctx, suggestions)
+}
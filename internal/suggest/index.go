@@ -0,0 +1,342 @@
+package suggest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/iamnotrodger/art-house-api/internal/model"
+)
+
+const (
+	// maxPrefix caps the prefixes indexed for a word, a longer prefix is
+	// looked up by its start
+	maxPrefix = 15
+	// ngramSize is the length of the ngrams that match the inside of a word
+	ngramSize = 3
+	// maxCandidates caps the suggestions matched by ngrams that are checked
+	// against what is typed
+	maxCandidates = 100
+	// batchSize is the number of suggestions written to redis at once
+	batchSize = 500
+)
+
+// Index serves suggestions from redis sorted sets. Every prefix and ngram of
+// the words of a suggestion is a sorted set of the suggestions that have it,
+// scored so that short texts and texts that start with the match rank first.
+// The index is rebuilt as a whole under a new generation of keys, which is
+// swapped in once it is complete.
+type Index struct {
+	client     *redis.Client
+	expiration time.Duration
+	namespace  string
+}
+
+// NewIndex returns the index of suggestions, a generation of the index expires
+// if it is not rebuilt within expiration
+func NewIndex(client *redis.Client, expiration time.Duration) *Index {
+	return &Index{
+		client:     client,
+		expiration: expiration,
+		namespace:  "suggest",
+	}
+}
+
+// Suggest returns up to limit suggestions for what is typed in a search. Every
+// word typed must start a word of a suggestion, and when there are not enough
+// of those the last word, which may still be being typed, can also fall
+// inside a word.
+func (i *Index) Suggest(ctx context.Context, text string, limit int64) ([]*model.Suggestion, error) {
+	words := tokenize(text)
+	if len(words) == 0 {
+		return []*model.Suggestion{}, nil
+	}
+
+	generation, err := i.client.Get(ctx, i.getKeyByGeneration()).Result()
+	if err == redis.Nil {
+		return []*model.Suggestion{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, word := range words {
+		keys = append(keys, i.getKeyByPrefix(generation, prefix(word)))
+	}
+	members, err := i.match(ctx, generation, keys, limit)
+	if err != nil {
+		return nil, err
+	}
+	suggestions, err := i.load(ctx, generation, members)
+	if err != nil {
+		return nil, err
+	}
+
+	last := words[len(words)-1]
+	if int64(len(suggestions)) >= limit || len([]rune(last)) < ngramSize {
+		return suggestions, nil
+	}
+
+	keys = keys[:len(keys)-1]
+	for _, ngram := range ngrams(last) {
+		keys = append(keys, i.getKeyByNgram(generation, ngram))
+	}
+	candidates, err := i.match(ctx, generation, keys, maxCandidates)
+	if err != nil {
+		return nil, err
+	}
+	candidates = exclude(candidates, members)
+	inside, err := i.load(ctx, generation, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	// ngrams can be spread across a word, only the words that hold the whole
+	// of what is typed are suggested
+	for _, suggestion := range inside {
+		if int64(len(suggestions)) >= limit {
+			break
+		}
+		if strings.Contains(strings.Join(tokenize(suggestion.Text), " "), last) {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	return suggestions, nil
+}
+
+// Rebuild replaces the index with the suggestions. Suggestions keep being
+// served from the previous generation of the index until the new one is complete.
+func (i *Index) Rebuild(ctx context.Context, suggestions []*model.Suggestion) error {
+	previous, err := i.client.Get(ctx, i.getKeyByGeneration()).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	generation := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	for start := 0; start < len(suggestions); start += batchSize {
+		end := start + batchSize
+		if end > len(suggestions) {
+			end = len(suggestions)
+		}
+		if err = i.write(ctx, generation, suggestions[start:end]); err != nil {
+			i.delete(ctx, generation)
+			return err
+		}
+	}
+
+	err = i.client.Set(ctx, i.getKeyByGeneration(), generation, 0).Err()
+	if err != nil {
+		return err
+	}
+	if previous == "" {
+		return nil
+	}
+	return i.delete(ctx, previous)
+}
+
+func (i *Index) write(ctx context.Context, generation string, suggestions []*model.Suggestion) error {
+	pipe := i.client.Pipeline()
+	keys := map[string]bool{i.getKeyByDocument(generation): true}
+
+	for _, suggestion := range suggestions {
+		member := getMember(suggestion)
+		doc, err := json.Marshal(suggestion)
+		if err != nil {
+			return err
+		}
+		pipe.HSet(ctx, i.getKeyByDocument(generation), member, doc)
+
+		prefixes, ngramScores := terms(suggestion.Text)
+		for term, score := range prefixes {
+			key := i.getKeyByPrefix(generation, term)
+			pipe.ZAdd(ctx, key, redis.Z{Score: score, Member: member})
+			keys[key] = true
+		}
+		for term, score := range ngramScores {
+			key := i.getKeyByNgram(generation, term)
+			pipe.ZAdd(ctx, key, redis.Z{Score: score, Member: member})
+			keys[key] = true
+		}
+	}
+	for key := range keys {
+		pipe.Expire(ctx, key, i.expiration)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// match returns up to count members of every sorted set, best score first.
+// The sets are intersected into a key of the generation that only lives for
+// the transaction, so that redis sorts and caps the members rather than every
+// member of the intersection being sent back.
+func (i *Index) match(ctx context.Context, generation string, keys []string, count int64) ([]string, error) {
+	if len(keys) == 1 {
+		return i.client.ZRevRange(ctx, keys[0], 0, count-1).Result()
+	}
+
+	key := i.getKeyByMatch(generation)
+	var members *redis.StringSliceCmd
+	_, err := i.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZInterStore(ctx, key, &redis.ZStore{Keys: keys, Aggregate: "MAX"})
+		members = pipe.ZRevRange(ctx, key, 0, count-1)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members.Val(), nil
+}
+
+// load returns the suggestions of the members in the order of the members
+func (i *Index) load(ctx context.Context, generation string, members []string) ([]*model.Suggestion, error) {
+	suggestions := []*model.Suggestion{}
+	if len(members) == 0 {
+		return suggestions, nil
+	}
+
+	docs, err := i.client.HMGet(ctx, i.getKeyByDocument(generation), members...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		// the generation can expire or be replaced while it is read
+		value, ok := doc.(string)
+		if !ok {
+			continue
+		}
+		var suggestion model.Suggestion
+		if err = json.Unmarshal([]byte(value), &suggestion); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	return suggestions, nil
+}
+
+// delete removes a generation of the index
+func (i *Index) delete(ctx context.Context, generation string) error {
+	iter := i.client.Scan(ctx, 0, i.getKeyPatternByGeneration(generation), 0).Iterator()
+	keys := []string{}
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) >= batchSize {
+			if err := i.client.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+			keys = []string{}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return i.client.Del(ctx, keys...).Err()
+}
+
+func (i *Index) getKeyByGeneration() string {
+	return fmt.Sprintf("%s:generation", i.namespace)
+}
+
+func (i *Index) getKeyByDocument(generation string) string {
+	return fmt.Sprintf("%s:%s:doc", i.namespace, generation)
+}
+
+func (i *Index) getKeyByPrefix(generation string, prefix string) string {
+	return fmt.Sprintf("%s:%s:prefix:%s", i.namespace, generation, prefix)
+}
+
+func (i *Index) getKeyByNgram(generation string, ngram string) string {
+	return fmt.Sprintf("%s:%s:ngram:%s", i.namespace, generation, ngram)
+}
+
+func (i *Index) getKeyByMatch(generation string) string {
+	return fmt.Sprintf("%s:%s:match", i.namespace, generation)
+}
+
+func (i *Index) getKeyPatternByGeneration(generation string) string {
+	return fmt.Sprintf("%s:%s:*", i.namespace, generation)
+}
+
+func getMember(suggestion *model.Suggestion) string {
+	return fmt.Sprintf("%s:%s", suggestion.Type, suggestion.ID.Hex())
+}
+
+// terms returns the prefixes and the ngrams of the words of a text with the
+// score of the text in their sorted sets. Shorter texts score higher, and a
+// prefix of the first word scores higher than the prefixes of the others.
+func terms(text string) (map[string]float64, map[string]float64) {
+	prefixes := map[string]float64{}
+	ngramScores := map[string]float64{}
+
+	score := 1 / float64(len([]rune(text)))
+	for position, word := range tokenize(text) {
+		wordScore := score
+		if position == 0 {
+			wordScore++
+		}
+		runes := []rune(word)
+		for end := 1; end <= len(runes) && end <= maxPrefix; end++ {
+			term := string(runes[:end])
+			if wordScore > prefixes[term] {
+				prefixes[term] = wordScore
+			}
+		}
+		for _, ngram := range ngrams(word) {
+			ngramScores[ngram] = score
+		}
+	}
+
+	return prefixes, ngramScores
+}
+
+// tokenize splits a text into its lower case words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func prefix(word string) string {
+	runes := []rune(word)
+	if len(runes) > maxPrefix {
+		return string(runes[:maxPrefix])
+	}
+	return word
+}
+
+func ngrams(word string) []string {
+	runes := []rune(word)
+	ngrams := []string{}
+	for start := 0; start+ngramSize <= len(runes); start++ {
+		ngrams = append(ngrams, string(runes[start:start+ngramSize]))
+	}
+	return ngrams
+}
+
+func exclude(members []string, excluded []string) []string {
+	remaining := []string{}
+	for _, member := range members {
+		found := false
+		for _, e := range excluded {
+			if member == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			remaining = append(remaining, member)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,42 @@
+package suggest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	require.Equal(t, []string{"the", "starry", "night", "1889"}, tokenize("The Starry-Night (1889)"))
+	require.Equal(t, []string{"édouard", "manet"}, tokenize(" Édouard  Manet "))
+	require.Equal(t, []string{}, tokenize(" - "))
+}
+
+func TestTerms(t *testing.T) {
+	prefixes, ngramScores := terms("Mona Monet")
+	score := 1 / float64(len("Mona Monet"))
+
+	require.Equal(t, map[string]float64{
+		"m":     score + 1,
+		"mo":    score + 1,
+		"mon":   score + 1,
+		"mona":  score + 1,
+		"mone":  score,
+		"monet": score,
+	}, prefixes)
+	require.Equal(t, map[string]float64{
+		"mon": score,
+		"ona": score,
+		"one": score,
+		"net": score,
+	}, ngramScores)
+
+	prefixes, _ = terms("Kunstgewerbemuseum")
+	require.Contains(t, prefixes, prefix("kunstgewerbemuseum"))
+	require.NotContains(t, prefixes, "kunstgewerbemuseum")
+}
+
+func TestExclude(t *testing.T) {
+	require.Equal(t, []string{"artist:b"}, exclude([]string{"artwork:a", "artist:b"}, []string{"artwork:a"}))
+	require.Equal(t, []string{}, exclude([]string{"artwork:a"}, []string{"artwork:a"}))
+}
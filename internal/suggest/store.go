@@ -0,0 +1,73 @@
+package suggest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/iamnotrodger/art-house-api/internal/query"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// source is where the suggestions of a type are read from
+type source struct {
+	suggestionType string
+	collection     string
+	field          string
+}
+
+var sources = []source{
+	{suggestionType: model.SearchArtwork, collection: "artworks", field: "title"},
+	{suggestionType: model.SearchArtist, collection: "artists", field: "name"},
+	{suggestionType: model.SearchExhibition, collection: "exhibitions", field: "name"},
+}
+
+type Store struct {
+	db *mongo.Database
+}
+
+func NewStore(db *mongo.Database) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// FindSuggestions returns the titles of the artworks and the names of the
+// artists and exhibitions that are not deleted
+func (s *Store) FindSuggestions(ctx context.Context) ([]*model.Suggestion, error) {
+	suggestions := []*model.Suggestion{}
+	for _, source := range sources {
+		found, err := s.find(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, found...)
+	}
+	return suggestions, nil
+}
+
+func (s *Store) find(ctx context.Context, source source) ([]*model.Suggestion, error) {
+	opts := options.Find().SetProjection(bson.D{{Key: source.field, Value: 1}})
+	cursor, err := s.db.Collection(source.collection).Find(ctx, query.NotDeletedFilter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	suggestions := []*model.Suggestion{}
+	for cursor.Next(ctx) {
+		id, ok := cursor.Current.Lookup("_id").ObjectIDOK()
+		if !ok {
+			return nil, fmt.Errorf("failed to unmarshal %s: missing _id", source.suggestionType)
+		}
+		text, ok := cursor.Current.Lookup(source.field).StringValueOK()
+		if !ok || text == "" {
+			continue
+		}
+		suggestions = append(suggestions, &model.Suggestion{Type: source.suggestionType, ID: id, Text: text})
+	}
+
+	return suggestions, cursor.Err()
+}
@@ -0,0 +1,76 @@
+package suggest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var (
+	MongoFailResponse    = bson.D{{Key: "ok", Value: 0}}
+	MongoFailRaw, _      = bson.Marshal(MongoFailResponse)
+	ErrMongoCommandError = mongo.CommandError{Message: "command failed", Raw: MongoFailRaw}
+)
+
+func TestFindSuggestions(t *testing.T) {
+	artworkID := primitive.NewObjectID()
+	artistID := primitive.NewObjectID()
+	exhibitionID := primitive.NewObjectID()
+
+	testCases := []struct {
+		name                string
+		dbResponse          []bson.D
+		expectedSuggestions []*model.Suggestion
+		expectedError       error
+	}{
+		{
+			name: "suggestions found",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: artworkID}, {Key: "title", Value: "Water Lilies"}},
+					bson.D{{Key: "_id", Value: primitive.NewObjectID()}},
+				),
+				mtest.CreateCursorResponse(0, "art-house.artists", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: artistID}, {Key: "name", Value: "Claude Monet"}},
+				),
+				mtest.CreateCursorResponse(0, "art-house.exhibitions", mtest.FirstBatch,
+					bson.D{{Key: "_id", Value: exhibitionID}, {Key: "name", Value: "Impressions"}},
+				),
+			},
+			expectedSuggestions: []*model.Suggestion{
+				{Type: model.SearchArtwork, ID: artworkID, Text: "Water Lilies"},
+				{Type: model.SearchArtist, ID: artistID, Text: "Claude Monet"},
+				{Type: model.SearchExhibition, ID: exhibitionID, Text: "Impressions"},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "find returns error",
+			dbResponse: []bson.D{
+				mtest.CreateCursorResponse(0, "art-house.artworks", mtest.FirstBatch),
+				MongoFailResponse,
+			},
+			expectedSuggestions: nil,
+			expectedError:       ErrMongoCommandError,
+		},
+	}
+
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	for _, tc := range testCases {
+		m.Run(tc.name, func(mt *mtest.T) {
+			mt.AddMockResponses(tc.dbResponse...)
+
+			store := NewStore(mt.DB)
+			suggestions, err := store.FindSuggestions(context.Background())
+			require.Equal(mt, tc.expectedError, err)
+			require.Equal(mt, tc.expectedSuggestions, suggestions)
+		})
+	}
+}
@@ -2,9 +2,15 @@ package util
 
 import (
 	"context"
+	"errors"
+	"log"
+	"strings"
 
 	"github.com/go-redis/redis/v9"
 	"github.com/iamnotrodger/art-house-api/cmd/config"
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -29,3 +35,97 @@ func GetRedisClient() *redis.Client {
 		DB:       config.Global.RedisDb,
 	})
 }
+
+// illegalOperation is the code of the error a standalone server returns for a
+// transaction, transactions need a replica set or a sharded cluster
+const illegalOperation = 20
+
+// WithTransaction runs fn in a transaction, so that the writes of fn are all
+// made or none of them are. fn must use the session context it is passed. A
+// standalone server cannot run transactions, fn then makes its writes in order
+// without one.
+func WithTransaction(ctx context.Context, client *mongo.Client, fn func(sessCtx mongo.SessionContext) error) error {
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	if !isTransactionUnsupported(err) {
+		return err
+	}
+
+	log.Println("transactions are not supported by the server, writing without one")
+	return mongo.WithSession(ctx, session, fn)
+}
+
+// isTransactionUnsupported reports whether the server rejected a transaction
+// because it is a standalone server. The first write of the transaction is
+// rejected, so none of its writes were made.
+func isTransactionUnsupported(err error) bool {
+	var commandErr mongo.CommandError
+	if !errors.As(err, &commandErr) {
+		return false
+	}
+	return commandErr.Code == illegalOperation && strings.Contains(commandErr.Message, "Transaction numbers")
+}
+
+// FindVersion returns the version of the document outside of the trash,
+// documents stored before they were versioned are at version 0
+func FindVersion(ctx context.Context, collection *mongo.Collection, documentID string) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(documentID)
+	if err != nil {
+		return 0, model.ErrInvalidID
+	}
+
+	var doc struct {
+		Version int64 `bson:"version"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"version": 1})
+	err = collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": nil}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, model.ErrNotFound
+	}
+	return doc.Version, err
+}
+
+// UpdateAndReturn applies the update to the document matching the filter and
+// returns the document as the update left it, or mongo.ErrNoDocuments when no
+// document matches
+func UpdateAndReturn(ctx context.Context, collection *mongo.Collection, filter interface{}, update interface{}) (bson.M, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var doc bson.M
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+	return doc, err
+}
+
+// ReplaceAndReturn replaces the document matching the filter and returns the
+// replacement as it was stored, or mongo.ErrNoDocuments when no document matches
+func ReplaceAndReturn(ctx context.Context, collection *mongo.Collection, filter interface{}, replacement interface{}) (bson.M, error) {
+	opts := options.FindOneAndReplace().SetReturnDocument(options.After)
+	var doc bson.M
+	err := collection.FindOneAndReplace(ctx, filter, replacement, opts).Decode(&doc)
+	return doc, err
+}
+
+// DeleteAndReturn deletes the document matching the filter and returns it as it
+// was stored, or mongo.ErrNoDocuments when no document matches
+func DeleteAndReturn(ctx context.Context, collection *mongo.Collection, filter interface{}) (bson.M, error) {
+	var doc bson.M
+	err := collection.FindOneAndDelete(ctx, filter).Decode(&doc)
+	return doc, err
+}
+
+// StoredDocument returns a document as it is stored, such as one that was just inserted
+func StoredDocument(doc interface{}) (bson.M, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var stored bson.M
+	err = bson.Unmarshal(raw, &stored)
+	return stored, err
+}
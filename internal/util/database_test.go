@@ -0,0 +1,71 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestWithTransactionStandalone(t *testing.T) {
+	m := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer m.Close()
+	m.Run("writes without a transaction", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCommandErrorResponse(mtest.CommandError{
+				Code:    illegalOperation,
+				Name:    "IllegalOperation",
+				Message: "Transaction numbers are only allowed on a replica set member or mongos",
+			}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+
+		calls := 0
+		err := WithTransaction(context.Background(), mt.Client, func(sessCtx mongo.SessionContext) error {
+			calls++
+			_, err := mt.Coll.InsertOne(sessCtx, bson.M{"name": "first"})
+			if err != nil {
+				return err
+			}
+			_, err = mt.Coll.InsertOne(sessCtx, bson.M{"name": "second"})
+			return err
+		})
+		require.NoError(mt, err)
+		require.Equal(mt, 2, calls)
+
+		inserts := []bson.Raw{}
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName == "insert" {
+				inserts = append(inserts, event.Command)
+			}
+		}
+		require.Len(mt, inserts, 3)
+		_, err = inserts[0].LookupErr("autocommit")
+		require.NoError(mt, err)
+		for _, insert := range inserts[1:] {
+			_, err := insert.LookupErr("autocommit")
+			require.Error(mt, err)
+		}
+	})
+
+	m.Run("other errors fail the transaction", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Message: "duplicate key"}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		calls := 0
+		err := WithTransaction(context.Background(), mt.Client, func(sessCtx mongo.SessionContext) error {
+			calls++
+			_, err := mt.Coll.InsertOne(sessCtx, bson.M{"name": "first"})
+			return err
+		})
+		require.Error(mt, err)
+		require.Equal(mt, 1, calls)
+	})
+}
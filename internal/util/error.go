@@ -1,32 +1,144 @@
 package util
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/iamnotrodger/art-house-api/internal/model"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func HandleError(w http.ResponseWriter, err error) {
-	var statusCode int
-	message := err.Error()
+// ProblemContentType is the content type of an RFC 7807 error response
+const ProblemContentType = "application/problem+json"
+
+// The codes of the problems, they are stable so that clients can match on them
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidParameters    = "invalid_parameters"
+	CodeInvalidID            = "invalid_id"
+	CodeNotFound             = "not_found"
+	CodeValidationFailed     = "validation_failed"
+	CodeConflict             = "conflict"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePreconditionRequired = "precondition_required"
+	CodeUnavailable          = "unavailable"
+	CodeInternal             = "internal"
+)
+
+// statusCodes are the codes of the problems that RespondWithError reports by status
+var statusCodes = map[int]string{
+	http.StatusBadRequest:           CodeBadRequest,
+	http.StatusNotFound:             CodeNotFound,
+	http.StatusConflict:             CodeConflict,
+	http.StatusPreconditionFailed:   CodePreconditionFailed,
+	http.StatusUnprocessableEntity:  CodeValidationFailed,
+	http.StatusPreconditionRequired: CodePreconditionRequired,
+	http.StatusServiceUnavailable:   CodeUnavailable,
+}
+
+// Problem is the body of an error response, as described by RFC 7807. Code
+// names the problem, and Errors lists the parameters or fields that caused it.
+type Problem struct {
+	Type   string      `json:"type"`
+	Title  string      `json:"title"`
+	Status int         `json:"status"`
+	Code   string      `json:"code"`
+	Detail string      `json:"detail,omitempty"`
+	Errors interface{} `json:"errors,omitempty"`
+}
+
+func NewProblem(statusCode int, code string, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// ProblemFromError returns the problem an error is reported as. Errors are
+// matched through their wrapping, and an error that is not of the domain is an
+// internal error whose message is logged rather than returned.
+func ProblemFromError(err error) *Problem {
+	var parametersErr *model.ParametersError
+	if errors.As(err, &parametersErr) {
+		problem := NewProblem(http.StatusBadRequest, CodeInvalidParameters, err.Error())
+		problem.Errors = parametersErr.Errors
+		return problem
+	}
+	var parameterErr *model.ParameterError
+	if errors.As(err, &parameterErr) {
+		problem := NewProblem(http.StatusBadRequest, CodeInvalidParameters, err.Error())
+		problem.Errors = []*model.ParameterError{parameterErr}
+		return problem
+	}
+	var validationErr *model.ValidationError
+	if errors.As(err, &validationErr) {
+		problem := NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, err.Error())
+		problem.Errors = []*model.ValidationError{validationErr}
+		return problem
+	}
+	var conflictErr *model.ConflictError
+	if errors.As(err, &conflictErr) {
+		return NewProblem(http.StatusConflict, CodeConflict, err.Error())
+	}
+	var preconditionErr *model.PreconditionError
+	if errors.As(err, &preconditionErr) {
+		return NewProblem(http.StatusPreconditionFailed, CodePreconditionFailed, err.Error())
+	}
 
-	switch err {
-	case mongo.ErrNilDocument:
-		statusCode = http.StatusBadRequest
-	case mongo.ErrNoDocuments:
-		statusCode = http.StatusNotFound
-	case primitive.ErrInvalidHex:
-		statusCode = http.StatusUnprocessableEntity
-		message = "Invalid ID"
-	default:
-		statusCode = http.StatusInternalServerError
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return NewProblem(http.StatusNotFound, CodeNotFound, "The document does not exist")
+	case errors.Is(err, model.ErrInvalidID):
+		return NewProblem(http.StatusUnprocessableEntity, CodeInvalidID, "Invalid ID")
+	case errors.Is(err, mongo.ErrNilDocument):
+		return NewProblem(http.StatusBadRequest, CodeBadRequest, err.Error())
+	case errors.Is(err, ErrPreconditionRequired):
+		return NewProblem(http.StatusPreconditionRequired, CodePreconditionRequired, err.Error())
+	case isUnavailable(err):
+		log.Println(err)
+		return NewProblem(http.StatusServiceUnavailable, CodeUnavailable, "The service is unavailable, try again later")
 	}
 
-	RespondWithError(w, statusCode, message)
+	log.Println(err)
+	return NewProblem(http.StatusInternalServerError, CodeInternal, "")
 }
 
+func HandleError(w http.ResponseWriter, err error) {
+	RespondWithProblem(w, ProblemFromError(err))
+}
+
+// RespondWithError reports a problem that is only known by its status
 func RespondWithError(w http.ResponseWriter, statusCode int, msg string) {
-	w.WriteHeader(statusCode)
-	w.Write([]byte(msg))
+	code, ok := statusCodes[statusCode]
+	if !ok {
+		code = CodeInternal
+	}
+	RespondWithProblem(w, NewProblem(statusCode, code, msg))
+}
+
+func RespondWithProblem(w http.ResponseWriter, problem *Problem) {
+	body, err := json.Marshal(problem)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(body)
+}
+
+// isUnavailable reports whether an error comes from a dependency that cannot
+// be reached or that did not answer in time
+func isUnavailable(err error) bool {
+	return errors.Is(err, model.ErrUnavailable) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		mongo.IsTimeout(err) ||
+		mongo.IsNetworkError(err)
 }
@@ -0,0 +1,77 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedBody string
+	}{
+		{
+			name:         "wrapped not found",
+			err:          fmt.Errorf("error decoding artist: %w", model.ErrNotFound),
+			expectedBody: `{"type":"about:blank","title":"Not Found","status":404,"code":"not_found","detail":"The document does not exist"}`,
+		},
+		{
+			name:         "invalid ID",
+			err:          model.ErrInvalidID,
+			expectedBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"code":"invalid_id","detail":"Invalid ID"}`,
+		},
+		{
+			name:         "validation",
+			err:          fmt.Errorf("artwork: %w", &model.ValidationError{Field: "title", Message: "is required"}),
+			expectedBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"code":"validation_failed","detail":"artwork: title is required","errors":[{"field":"title","message":"is required"}]}`,
+		},
+		{
+			name:         "parameter",
+			err:          &model.ParameterError{Parameter: "sort", Message: "is invalid"},
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"code":"invalid_parameters","detail":"sort is invalid","errors":[{"parameter":"sort","message":"is invalid"}]}`,
+		},
+		{
+			name:         "conflict",
+			err:          &model.ConflictError{Message: "artist has artworks"},
+			expectedBody: `{"type":"about:blank","title":"Conflict","status":409,"code":"conflict","detail":"artist has artworks"}`,
+		},
+		{
+			name:         "precondition required",
+			err:          ErrPreconditionRequired,
+			expectedBody: `{"type":"about:blank","title":"Precondition Required","status":428,"code":"precondition_required","detail":"If-Match header is required"}`,
+		},
+		{
+			name:         "unavailable",
+			err:          fmt.Errorf("failed to find artworks: %w", context.DeadlineExceeded),
+			expectedBody: `{"type":"about:blank","title":"Service Unavailable","status":503,"code":"unavailable","detail":"The service is unavailable, try again later"}`,
+		},
+		{
+			name:         "internal",
+			err:          fmt.Errorf("failed to unmarshal artworks: %w", fmt.Errorf("bad document")),
+			expectedBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"code":"internal"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			w.Header().Set("Content-Type", "application/json")
+			HandleError(w, tt.err)
+
+			require.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+			require.JSONEq(t, tt.expectedBody, w.Body.String())
+		})
+	}
+
+	w := httptest.NewRecorder()
+	RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"code":"bad_request","detail":"Invalid request body"}`, w.Body.String())
+}
@@ -0,0 +1,101 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+)
+
+// ErrPreconditionRequired is returned when a write is made without an If-Match header
+var ErrPreconditionRequired = errors.New("If-Match header is required")
+
+var errVersionMismatch = &model.PreconditionError{Message: "If-Match does not match the current version"}
+
+// ETag returns a strong ETag over the response body
+func ETag(body []byte) string {
+	return fmt.Sprintf(`"%s"`, hashBody(body))
+}
+
+// VersionedETag returns a strong ETag over the response body of a document, prefixed
+// with the document's version so that writes can be matched against it
+func VersionedETag(version int64, body []byte) string {
+	return fmt.Sprintf(`"%d-%s"`, version, hashBody(body))
+}
+
+// GetIfMatch returns the version of the document the write was made against.
+// The header is a list of ETags, any of which can match the current version of
+// the document, or * for whichever version is current. current is only read
+// to match * or a list. Weak ETags never match, as If-Match compares strongly.
+func GetIfMatch(r *http.Request, current func() (int64, error)) (int64, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		return 0, ErrPreconditionRequired
+	}
+	if ifMatch == "*" {
+		return current()
+	}
+
+	versions := []int64{}
+	for _, etag := range strings.Split(ifMatch, ",") {
+		if version, ok := parseVersion(strings.TrimSpace(etag)); ok {
+			versions = append(versions, version)
+		}
+	}
+	switch len(versions) {
+	case 0:
+		return 0, errVersionMismatch
+	case 1:
+		return versions[0], nil
+	}
+
+	version, err := current()
+	if err != nil {
+		return 0, err
+	}
+	for _, candidate := range versions {
+		if candidate == version {
+			return version, nil
+		}
+	}
+	return 0, errVersionMismatch
+}
+
+// parseVersion returns the version of a strong versioned ETag
+func parseVersion(etag string) (int64, bool) {
+	if strings.HasPrefix(etag, "W/") {
+		return 0, false
+	}
+
+	etag = strings.Trim(etag, `"`)
+	if i := strings.Index(etag, "-"); i >= 0 {
+		etag = etag[:i]
+	}
+
+	version, err := strconv.ParseInt(etag, 10, 64)
+	if err != nil || version < 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// matchesETag reports whether an If-None-Match header matches the ETag, using weak comparison
+func matchesETag(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,132 @@
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+)
+
+// NextCursorHeader carries the cursor of the next page of a list
+const NextCursorHeader = "X-Next-Cursor"
+
+// envelope wraps a page of a list with where the page sits in the full list,
+// lists are wrapped when they are requested with ?envelope=true or with facets
+type envelope struct {
+	Data   interface{} `json:"data"`
+	Total  int64       `json:"total"`
+	Limit  int64       `json:"limit"`
+	Skip   int64       `json:"skip"`
+	Next   string      `json:"next,omitempty"`
+	Facets interface{} `json:"facets,omitempty"`
+}
+
+// NewPageResponse encodes a page of a list along with the links to the pages
+// around it and the cursor of the next page
+func NewPageResponse(r *http.Request, v interface{}, page *model.Page) (*Response, error) {
+	return newPageResponse(r, v, page, nil)
+}
+
+// NewFacetedPageResponse encodes a page of a list like NewPageResponse, in an
+// envelope next to the facets of the full list
+func NewFacetedPageResponse(r *http.Request, v interface{}, page *model.Page, facets interface{}) (*Response, error) {
+	return newPageResponse(r, v, page, facets)
+}
+
+func newPageResponse(r *http.Request, v interface{}, page *model.Page, facets interface{}) (*Response, error) {
+	var res *Response
+	var err error
+	if wantsEnvelope(r) || facets != nil {
+		res, err = NewResponse(&envelope{
+			Data:   v,
+			Total:  page.Total,
+			Limit:  page.Limit,
+			Skip:   page.Skip,
+			Next:   page.NextCursor,
+			Facets: facets,
+		})
+	} else {
+		res, err = NewResponse(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res.Header = http.Header{}
+	if page.NextCursor != "" {
+		res.Header.Set(NextCursorHeader, page.NextCursor)
+	}
+	if links := pageLinks(r.URL, page); links != "" {
+		res.Header.Set("Link", links)
+	}
+
+	return res, nil
+}
+
+// RespondWithPage encodes a page of a list and writes it with its ETag and links
+func RespondWithPage(w http.ResponseWriter, r *http.Request, statusCode int, v interface{}, page *model.Page) {
+	res, err := NewPageResponse(r, v, page)
+	if err != nil {
+		HandleError(w, err)
+		return
+	}
+
+	res.Write(w, r, statusCode)
+}
+
+func wantsEnvelope(r *http.Request) bool {
+	wants, err := strconv.ParseBool(r.URL.Query().Get("envelope"))
+	return err == nil && wants
+}
+
+// pageLinks returns the RFC 8288 Link header of the page. The next page is
+// reached with its cursor when it has one, the other pages with skip. A page
+// reached with a cursor does not know its offset and so has no previous page.
+func pageLinks(u *url.URL, page *model.Page) string {
+	if page.Limit <= 0 {
+		return ""
+	}
+
+	query := u.Query()
+	links := []string{}
+	link := func(rel string, skip int64, cursor string) {
+		values := url.Values{}
+		for key, value := range query {
+			values[key] = value
+		}
+		values.Del("skip")
+		values.Del("cursor")
+		if skip > 0 {
+			values.Set("skip", strconv.FormatInt(skip, 10))
+		}
+		if cursor != "" {
+			values.Set("cursor", cursor)
+		}
+
+		target := url.URL{Path: u.Path, RawQuery: values.Encode()}
+		links = append(links, fmt.Sprintf("<%s>; rel=\"%s\"", target.String(), rel))
+	}
+
+	link("first", 0, "")
+	if page.Skip > 0 && query.Get("cursor") == "" {
+		prev := page.Skip - page.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		link("prev", prev, "")
+	}
+	if page.NextCursor != "" {
+		link("next", 0, page.NextCursor)
+	} else if query.Get("cursor") == "" && page.Skip+page.Limit < page.Total {
+		// sorts that a cursor cannot page, such as relevance, fall back to skip
+		link("next", page.Skip+page.Limit, "")
+	}
+	if page.Total > 0 {
+		link("last", (page.Total-1)/page.Limit*page.Limit, "")
+	}
+
+	return strings.Join(links, ", ")
+}
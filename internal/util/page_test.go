@@ -0,0 +1,53 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPageResponse(t *testing.T) {
+	page := &model.Page{Total: 7, Limit: 2, Skip: 2, NextCursor: "cursor"}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/artwork?limit=2&skip=2", nil)
+	res, err := NewPageResponse(r, []int{1, 2}, page)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	res.Write(rec, r, http.StatusOK)
+	require.Equal(t, "cursor", rec.Header().Get(NextCursorHeader))
+	require.Equal(t, res.ETag, rec.Header().Get("ETag"))
+	require.Equal(t, `</api/artwork?limit=2>; rel="first", `+
+		`</api/artwork?limit=2>; rel="prev", `+
+		`</api/artwork?cursor=cursor&limit=2>; rel="next", `+
+		`</api/artwork?limit=2&skip=6>; rel="last"`, rec.Header().Get("Link"))
+	require.Equal(t, "[1,2]", rec.Body.String())
+
+	r = httptest.NewRequest(http.MethodGet, "/api/artwork?envelope=true&cursor=previous", nil)
+	res, err = NewPageResponse(r, []int{1, 2}, &model.Page{Total: 2, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, `{"data":[1,2],"total":2,"limit":2,"skip":0}`, string(res.Body))
+	require.Equal(t, "", res.Header.Get(NextCursorHeader))
+	require.Equal(t, `</api/artwork?envelope=true>; rel="first", `+
+		`</api/artwork?envelope=true>; rel="last"`, res.Header.Get("Link"))
+
+	res, err = NewPageResponse(r, []int{1, 2}, page)
+	require.NoError(t, err)
+	require.Equal(t, `{"data":[1,2],"total":7,"limit":2,"skip":2,"next":"cursor"}`, string(res.Body))
+
+	r = httptest.NewRequest(http.MethodGet, "/api/artists?search=monet&sort=score:desc", nil)
+	res, err = NewPageResponse(r, []int{1, 2}, &model.Page{Total: 3, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, `</api/artists?search=monet&sort=score%3Adesc>; rel="first", `+
+		`</api/artists?search=monet&skip=2&sort=score%3Adesc>; rel="next", `+
+		`</api/artists?search=monet&skip=2&sort=score%3Adesc>; rel="last"`, res.Header.Get("Link"))
+
+	r = httptest.NewRequest(http.MethodGet, "/api/artwork?facets=decade", nil)
+	facets := map[string][]int{"decade": {1880}}
+	res, err = NewFacetedPageResponse(r, []int{1, 2}, &model.Page{Total: 2, Limit: 2}, facets)
+	require.NoError(t, err)
+	require.Equal(t, `{"data":[1,2],"total":2,"limit":2,"skip":0,"facets":{"decade":[1880]}}`, string(res.Body))
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Response is an encoded JSON response body and its headers, responses are
+// cached as is so that a cache hit does not need to encode the body again
+type Response struct {
+	ETag   string          `json:"etag"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body"`
+}
+
+func NewResponse(v interface{}) (*Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{ETag: ETag(body), Body: body}, nil
+}
+
+// NewVersionedResponse encodes a document at version, see VersionedETag
+func NewVersionedResponse(v interface{}, version int64) (*Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{ETag: VersionedETag(version, body), Body: body}, nil
+}
+
+// Write writes the response, or 304 Not Modified when the ETag matches the
+// If-None-Match header of a read
+func (res *Response) Write(w http.ResponseWriter, r *http.Request, statusCode int) {
+	for key, values := range res.Header {
+		w.Header()[key] = values
+	}
+	w.Header().Set("ETag", res.ETag)
+
+	isRead := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if isRead && statusCode == http.StatusOK && matchesETag(r.Header.Get("If-None-Match"), res.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(statusCode)
+	w.Write(res.Body)
+}
+
+// RespondWithJSON encodes v and writes it with its ETag
+func RespondWithJSON(w http.ResponseWriter, r *http.Request, statusCode int, v interface{}) {
+	res, err := NewResponse(v)
+	if err != nil {
+		HandleError(w, err)
+		return
+	}
+
+	res.Write(w, r, statusCode)
+}
@@ -0,0 +1,159 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseWrite(t *testing.T) {
+	res, err := NewVersionedResponse(map[string]string{"title": "title"}, 3)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name               string
+		method             string
+		ifNoneMatch        string
+		statusCode         int
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "no If-None-Match",
+			method:             http.MethodGet,
+			statusCode:         http.StatusOK,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"title":"title"}`,
+		},
+		{
+			name:               "matching ETag",
+			method:             http.MethodGet,
+			ifNoneMatch:        `"other", ` + res.ETag,
+			statusCode:         http.StatusOK,
+			expectedStatusCode: http.StatusNotModified,
+			expectedBody:       "",
+		},
+		{
+			name:               "weak matching ETag",
+			method:             http.MethodGet,
+			ifNoneMatch:        "W/" + res.ETag,
+			statusCode:         http.StatusOK,
+			expectedStatusCode: http.StatusNotModified,
+			expectedBody:       "",
+		},
+		{
+			name:               "stale ETag",
+			method:             http.MethodGet,
+			ifNoneMatch:        `"other"`,
+			statusCode:         http.StatusOK,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"title":"title"}`,
+		},
+		{
+			name:               "write response",
+			method:             http.MethodPost,
+			ifNoneMatch:        res.ETag,
+			statusCode:         http.StatusCreated,
+			expectedStatusCode: http.StatusCreated,
+			expectedBody:       `{"title":"title"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, "/api/artwork/1", nil)
+			if test.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", test.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+
+			res.Write(w, r, test.statusCode)
+			require.Equal(t, test.expectedStatusCode, w.Code)
+			require.Equal(t, test.expectedBody, w.Body.String())
+			require.Equal(t, res.ETag, w.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestGetIfMatch(t *testing.T) {
+	body := []byte(`{"title":"title"}`)
+	stale := &model.PreconditionError{Message: "If-Match does not match the current version"}
+
+	tests := []struct {
+		name            string
+		ifMatch         string
+		current         int64
+		currentErr      error
+		expectedVersion int64
+		expectedError   error
+	}{
+		{
+			name:          "missing If-Match",
+			ifMatch:       "",
+			expectedError: ErrPreconditionRequired,
+		},
+		{
+			name:            "versioned ETag",
+			ifMatch:         VersionedETag(3, body),
+			expectedVersion: 3,
+		},
+		{
+			name:          "ETag without version",
+			ifMatch:       ETag(body),
+			expectedError: stale,
+		},
+		{
+			name:          "weak ETag",
+			ifMatch:       "W/" + VersionedETag(3, body),
+			expectedError: stale,
+		},
+		{
+			name:            "any version",
+			ifMatch:         "*",
+			current:         4,
+			expectedVersion: 4,
+		},
+		{
+			name:          "any version of a missing document",
+			ifMatch:       "*",
+			currentErr:    model.ErrNotFound,
+			expectedError: model.ErrNotFound,
+		},
+		{
+			name:            "list with the current version",
+			ifMatch:         VersionedETag(3, body) + ", " + VersionedETag(4, body),
+			current:         4,
+			expectedVersion: 4,
+		},
+		{
+			name:          "list without the current version",
+			ifMatch:       VersionedETag(3, body) + ", " + VersionedETag(4, body),
+			current:       5,
+			expectedError: stale,
+		},
+		{
+			name:            "list with a weak ETag",
+			ifMatch:         "W/" + VersionedETag(4, body) + ", " + VersionedETag(3, body),
+			current:         4,
+			expectedVersion: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/api/artwork/1", nil)
+			if test.ifMatch != "" {
+				r.Header.Set("If-Match", test.ifMatch)
+			}
+
+			version, err := GetIfMatch(r, func() (int64, error) {
+				return test.current, test.currentErr
+			})
+			require.Equal(t, test.expectedError, err)
+			require.Equal(t, test.expectedVersion, version)
+		})
+	}
+}
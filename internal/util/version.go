@@ -0,0 +1,47 @@
+package util
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/iamnotrodger/art-house-api/cmd/config"
+)
+
+const (
+	// APIVersionHeader picks the version of the API a request is served by,
+	// requests without it are served by the first version
+	APIVersionHeader = "API-Version"
+	// StrictAPIVersion is the first version of the API that checks the query
+	// parameters of a request strictly
+	StrictAPIVersion = 2
+)
+
+// ParameterChecker is a query that can check its parameters strictly
+type ParameterChecker interface {
+	CheckParameters() error
+}
+
+// APIVersion returns the version of the API that serves a request
+func APIVersion(r *http.Request) int {
+	version, err := strconv.Atoi(r.Header.Get(APIVersionHeader))
+	if err != nil || version < 1 {
+		return 1
+	}
+	return version
+}
+
+// IsStrict reports whether the query parameters of a request are checked
+// strictly, which they are from StrictAPIVersion on or when it is configured
+// for every version
+func IsStrict(r *http.Request) bool {
+	return config.Global.StrictParameters || APIVersion(r) >= StrictAPIVersion
+}
+
+// CheckParameters rejects the unknown and invalid query parameters of a strict
+// request, every parameter is let through otherwise
+func CheckParameters(r *http.Request, query ParameterChecker) error {
+	if !IsStrict(r) {
+		return nil
+	}
+	return query.CheckParameters()
+}
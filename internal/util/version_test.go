@@ -0,0 +1,67 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iamnotrodger/art-house-api/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+type checker struct {
+	err error
+}
+
+func (c checker) CheckParameters() error {
+	return c.err
+}
+
+func TestCheckParameters(t *testing.T) {
+	rejected := checker{err: &model.ParametersError{Errors: []*model.ParameterError{
+		{Parameter: "limit", Reason: model.ReasonInvalid, Message: "must be a number"},
+		{Parameter: "colour", Reason: model.ReasonUnknown, Message: "is not a parameter of this request"},
+	}}}
+
+	tests := []struct {
+		name          string
+		version       string
+		expectedError bool
+	}{
+		{name: "no version", version: "", expectedError: false},
+		{name: "first version", version: "1", expectedError: false},
+		{name: "invalid version", version: "two", expectedError: false},
+		{name: "strict version", version: "2", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/artworks?limit=many&colour=red", nil)
+			if tt.version != "" {
+				r.Header.Set(APIVersionHeader, tt.version)
+			}
+
+			err := CheckParameters(r, rejected)
+			if !tt.expectedError {
+				require.NoError(t, err)
+				return
+			}
+			require.Equal(t, rejected.err, err)
+
+			w := httptest.NewRecorder()
+			HandleError(w, err)
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			require.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+			require.JSONEq(t, `{
+				"type":"about:blank",
+				"title":"Bad Request",
+				"status":400,
+				"code":"invalid_parameters",
+				"detail":"limit must be a number; colour is not a parameter of this request",
+				"errors":[
+				{"parameter":"limit","reason":"invalid","message":"must be a number"},
+				{"parameter":"colour","reason":"unknown","message":"is not a parameter of this request"}
+			]}`, w.Body.String())
+		})
+	}
+}